@@ -14,21 +14,46 @@ type MetricCollector interface {
 	Collecting(method string, f func() error) func() error
 }
 
-const (
-	labelFunc = "func" // Value: caller's func/method name.
-)
+// LabeledMetricCollector is a MetricCollector which can collect metrics
+// with additional labels (e.g.: tenant or shard).
+type LabeledMetricCollector interface {
+	MetricCollector
+	// CollectingWith collects Metrics information for handlers with additional labels.
+	// Labels which weren't registered in collector are ignored.
+	CollectingWith(method string, labels map[string]string, f func() error) func() error
+}
+
+// LabelFunc is a metric label which contains caller's func/method name.
+const LabelFunc = "func"
 
-var _ MetricCollector = Metrics{}
+// BugCollector is a MetricCollector which can count programming bugs
+// (e.g.: sqlx errors like `missing destination …`).
+type BugCollector interface {
+	MetricCollector
+	// Bug counts programming bug in method.
+	Bug(method string)
+}
+
+var (
+	_ LabeledMetricCollector = Metrics{}
+	_ BugCollector           = Metrics{}
+)
 
 // Metrics contains general metrics for DAL methods.
 type Metrics struct {
 	callErrTotal *prometheus.CounterVec
 	callDuration *prometheus.HistogramVec
+	bugTotal     *prometheus.CounterVec
+	labels       []string
 }
 
 // NewMetrics registers and returns common DAL metrics used by all
 // services (namespace).
-func NewMetrics(reg *prometheus.Registry, namespace, subsystem string, methodsFrom interface{}) (metric Metrics) {
+// Additional labels may be set by CollectingWith, otherwise they are empty.
+func NewMetrics(reg *prometheus.Registry, namespace, subsystem string, methodsFrom interface{}, labels ...string) (metric Metrics) {
+	metric.labels = labels
+	labelNames := append([]string{LabelFunc}, labels...)
+
 	metric.callErrTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: namespace,
@@ -36,7 +61,7 @@ func NewMetrics(reg *prometheus.Registry, namespace, subsystem string, methodsFr
 			Name:      "errors_total",
 			Help:      "Amount of DAL errors.",
 		},
-		[]string{labelFunc},
+		labelNames,
 	)
 	reg.MustRegister(metric.callErrTotal)
 	metric.callDuration = prometheus.NewHistogramVec(
@@ -46,16 +71,25 @@ func NewMetrics(reg *prometheus.Registry, namespace, subsystem string, methodsFr
 			Name:      "call_duration_seconds",
 			Help:      "DAL call latency.",
 		},
-		[]string{labelFunc},
+		labelNames,
 	)
 	reg.MustRegister(metric.callDuration)
+	metric.bugTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "bugs_total",
+			Help:      "Amount of DAL errors which are actually programming bugs.",
+		},
+		[]string{LabelFunc},
+	)
+	reg.MustRegister(metric.bugTotal)
 
 	for _, methodName := range reflectx.MethodsOf(methodsFrom) {
-		l := prometheus.Labels{
-			labelFunc: methodName,
-		}
+		l := metric.prometheusLabels(methodName, nil)
 		metric.callErrTotal.With(l)
 		metric.callDuration.With(l)
+		metric.bugTotal.With(prometheus.Labels{LabelFunc: methodName})
 	}
 
 	return metric
@@ -63,9 +97,14 @@ func NewMetrics(reg *prometheus.Registry, namespace, subsystem string, methodsFr
 
 // Collecting implements MetricCollector.
 func (m Metrics) Collecting(method string, f func() error) func() error {
+	return m.CollectingWith(method, nil, f)
+}
+
+// CollectingWith implements LabeledMetricCollector.
+func (m Metrics) CollectingWith(method string, labels map[string]string, f func() error) func() error {
 	return func() (err error) {
 		start := time.Now()
-		l := prometheus.Labels{labelFunc: method}
+		l := m.prometheusLabels(method, labels)
 		defer func() {
 			m.callDuration.With(l).Observe(time.Since(start).Seconds())
 			if err != nil {
@@ -79,12 +118,37 @@ func (m Metrics) Collecting(method string, f func() error) func() error {
 	}
 }
 
-var _ MetricCollector = NoMetric{}
+// Bug implements BugCollector.
+func (m Metrics) Bug(method string) {
+	m.bugTotal.With(prometheus.Labels{LabelFunc: method}).Inc()
+}
+
+func (m Metrics) prometheusLabels(method string, labels map[string]string) prometheus.Labels {
+	l := prometheus.Labels{LabelFunc: method}
+	for _, name := range m.labels {
+		l[name] = labels[name]
+	}
+
+	return l
+}
+
+var (
+	_ LabeledMetricCollector = NoMetric{}
+	_ BugCollector           = NoMetric{}
+)
 
 // NoMetric if you want to turn off metrics.
 type NoMetric struct{}
 
 // Collecting implements MetricCollector.
-func (n NoMetric) Collecting(_ string, _ func() error) func() error {
-	return func() error { return nil }
+func (n NoMetric) Collecting(_ string, f func() error) func() error {
+	return f
 }
+
+// CollectingWith implements LabeledMetricCollector.
+func (n NoMetric) CollectingWith(_ string, _ map[string]string, f func() error) func() error {
+	return f
+}
+
+// Bug implements BugCollector.
+func (n NoMetric) Bug(_ string) {}
@@ -0,0 +1,24 @@
+package repo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo"
+)
+
+func TestNoMetric_Collecting(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	errAny := errors.New("any error")
+	called := false
+	err := repo.NoMetric{}.Collecting("Method", func() error {
+		called = true
+		return errAny
+	})()
+	r.True(called)
+	r.ErrorIs(err, errAny)
+}
@@ -0,0 +1,156 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Fault is a kind of fault injected by Chaos.
+type Fault uint8
+
+// Enum.
+const (
+	_ Fault = iota
+	// FaultLatency delays DAL method call by ChaosRule.Latency.
+	FaultLatency
+	// FaultSerializationFailure returns pq.Error with serialization_failure code,
+	// like CockroachDB returns for transaction which must be retried.
+	FaultSerializationFailure
+	// FaultConnectionFailure returns pq.Error with connection_failure code.
+	FaultConnectionFailure
+	// FaultUniqueViolation returns pq.Error with unique_violation code.
+	FaultUniqueViolation
+	// FaultDropConnection closes connection of DAL method call and returns
+	// driver.ErrBadConn, DB.NoTx closes one of idle pool connections.
+	FaultDropConnection
+)
+
+// ChaosRule describes fault which is injected into DAL method calls.
+type ChaosRule struct {
+	// Method is a DAL method name (as resolved by reflectx.CallerMethodName),
+	// empty value matches all methods.
+	Method string
+	// Probability of fault in [0, 1].
+	Probability float64
+	Fault       Fault
+	// Latency is used by FaultLatency.
+	Latency time.Duration
+}
+
+// Chaos injects faults into DAL method calls for testing how service behaves
+// when database misbehaves. It's deterministic for the same seed and
+// sequence of calls.
+type Chaos struct {
+	rules []ChaosRule
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewChaos build and returns new Chaos.
+func NewChaos(seed int64, rules ...ChaosRule) *Chaos {
+	return &Chaos{
+		rules: rules,
+		rand:  rand.New(rand.NewSource(seed)), //nolint:gosec // Reproducibility is required.
+	}
+}
+
+// faults returns faults which must be injected into method call.
+func (c *Chaos) faults(methodName string) []ChaosRule {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var faults []ChaosRule
+	for _, rule := range c.rules {
+		if rule.Method != "" && rule.Method != methodName {
+			continue
+		}
+		if c.rand.Float64() < rule.Probability {
+			faults = append(faults, rule)
+		}
+	}
+
+	return faults
+}
+
+// inject injects faults into method call. Returns error if call must fail,
+// connection must be dropped (see dropConn) if error is driver.ErrBadConn.
+func (c *Chaos) inject(ctx context.Context, methodName string) error {
+	for _, rule := range c.faults(methodName) {
+		var err error
+		switch rule.Fault {
+		case FaultLatency:
+			err = sleep(ctx, rule.Latency)
+		case FaultSerializationFailure:
+			err = chaosErr(severityError, "40001", "restart transaction: TransactionRetryWithProtoRefreshError")
+		case FaultConnectionFailure:
+			err = chaosErr(pq.Efatal, "08006", "connection reset by peer")
+		case FaultUniqueViolation:
+			err = chaosErr(severityError, "23505", "duplicate key value violates unique constraint")
+		case FaultDropConnection:
+			err = fmt.Errorf("chaos: %w", driver.ErrBadConn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropConn makes database/sql close connection instead of returning it to pool.
+func dropConn(conn *sqlx.Conn) {
+	_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+}
+
+// dropPoolConn closes one of idle pool connections, it's used by NoTx which
+// doesn't pin connection. Nothing is dropped if pool has no idle connections,
+// but concurrent calls may take the idle one first, then it dials
+// a new connection just for closing it.
+func (db *DB) dropPoolConn(ctx context.Context) {
+	if db.conn.Stats().Idle == 0 {
+		return
+	}
+
+	conn, err := db.conn.Connx(ctx)
+	if err == nil {
+		dropConn(conn)
+	}
+}
+
+// severityError is a severity of errors which don't close connection,
+// lib/pq has constants only for other severities.
+const severityError = "ERROR"
+
+// chaosErr returns error like database returns, severity must be the same
+// as real one, because code may depend on it.
+func chaosErr(severity string, code pq.ErrorCode, message string) error {
+	return &pq.Error{
+		Severity: severity,
+		Code:     code,
+		Message:  "chaos: " + message,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
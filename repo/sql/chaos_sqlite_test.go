@@ -0,0 +1,72 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+func TestChaos_DropConnection(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{"NoTxMethod", "TxMethod", "ConnMethod"}
+	for _, method := range tests {
+		method := method
+		t.Run(method, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+
+			chaos := sql.NewChaos(1, sql.ChaosRule{Method: method, Probability: 1, Fault: sql.FaultDropConnection})
+			db, _ := newRecordingSQLite(t, sql.Config{Chaos: chaos})
+			repo := testRepo{db: db}
+			calls := map[string]func(context.Context) (int, error){
+				"NoTxMethod": repo.NoTxMethod,
+				"TxMethod":   repo.TxMethod,
+				"ConnMethod": repo.ConnMethod,
+			}
+			r.Equal(1, db.Stats().OpenConnections)
+
+			_, err := calls[method](ctx)
+			r.ErrorIs(err, driver.ErrBadConn)
+			r.Equal(0, db.Stats().OpenConnections)
+		})
+	}
+}
+
+func TestChaos_DropConnection_NoIdle(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	chaos := sql.NewChaos(1, sql.ChaosRule{Probability: 1, Fault: sql.FaultDropConnection})
+	db, recording := newRecordingSQLite(t, sql.Config{Chaos: chaos})
+	repo := testRepo{db: db}
+	r.Equal(1, recording.connections())
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.NoTxMethod(ctx)
+		r.ErrorIs(err, driver.ErrBadConn)
+		r.Equal(0, db.Stats().OpenConnections)
+	}
+	r.Equal(1, recording.connections(), "nothing is dialed for dropping")
+}
+
+func TestChaos_NoTxContext(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	chaos := sql.NewChaos(1, sql.ChaosRule{Probability: 1, Fault: sql.FaultLatency, Latency: time.Hour})
+	db, recording := newRecordingSQLite(t, sql.Config{Chaos: chaos})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := testRepo{db: db}.NoTxMethod(ctx)
+	r.ErrorIs(err, context.Canceled)
+	r.Empty(recording.all())
+}
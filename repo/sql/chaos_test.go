@@ -0,0 +1,72 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaos_Inject(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := map[string]struct {
+		rule         ChaosRule
+		wantCode     pq.ErrorCode
+		wantSeverity string
+		wantErr      error
+	}{
+		"serialization_failure": {ChaosRule{Fault: FaultSerializationFailure, Probability: 1}, "40001", "ERROR", nil},
+		"connection_failure":    {ChaosRule{Fault: FaultConnectionFailure, Probability: 1}, "08006", pq.Efatal, nil},
+		"unique_violation":      {ChaosRule{Fault: FaultUniqueViolation, Probability: 1}, "23505", "ERROR", nil},
+		"drop_connection":       {ChaosRule{Fault: FaultDropConnection, Probability: 1}, "", "", driver.ErrBadConn},
+		"latency":               {ChaosRule{Fault: FaultLatency, Probability: 1, Latency: time.Millisecond}, "", "", nil},
+		"other_method":          {ChaosRule{Method: "Other", Fault: FaultUniqueViolation, Probability: 1}, "", "", nil},
+		"never":                 {ChaosRule{Fault: FaultUniqueViolation}, "", "", nil},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			err := NewChaos(1, tc.rule).inject(ctx, "Method")
+			pqErr := new(pq.Error)
+			switch {
+			case tc.wantCode != "":
+				r.True(errors.As(err, &pqErr))
+				r.Equal(tc.wantCode, pqErr.Code)
+				r.Equal(tc.wantSeverity, pqErr.Severity)
+			case tc.wantErr != nil:
+				r.ErrorIs(err, tc.wantErr)
+			default:
+				r.NoError(err)
+			}
+		})
+	}
+}
+
+func TestChaos_Deterministic(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	results := func(seed int64) (faults []bool) {
+		chaos := NewChaos(seed, ChaosRule{Fault: FaultUniqueViolation, Probability: 0.5})
+		for i := 0; i < 100; i++ {
+			faults = append(faults, chaos.inject(context.Background(), "Method") != nil)
+		}
+		return faults
+	}
+
+	r.Equal(results(42), results(42))
+	r.NotEqual(results(42), results(43))
+	r.Contains(results(42), true)
+	r.Contains(results(42), false)
+	r.Nil((*Chaos)(nil).inject(context.Background(), "Method"))
+}
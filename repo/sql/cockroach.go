@@ -0,0 +1,166 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNegativeTimeout is returned by DB.Tx for negative timeout in CockroachTxOptions.
+var ErrNegativeTimeout = errors.New("negative timeout")
+
+// FollowerReadTimestamp is a CockroachDB function which returns timestamp
+// that is likely safe for follower reads.
+const FollowerReadTimestamp = "follower_read_timestamp()"
+
+// CockroachPriority is a CockroachDB transaction priority.
+type CockroachPriority uint8
+
+// Enum.
+const (
+	_                       CockroachPriority = iota
+	CockroachPriorityLow                      // LOW
+	CockroachPriorityNormal                   // NORMAL
+	CockroachPriorityHigh                     // HIGH
+)
+
+// CockroachTxOptions contains CockroachDB specific transaction options,
+// they're applied by DB.Tx right after BEGIN.
+// Zero values mean that option isn't set and database default is used.
+type CockroachTxOptions struct {
+	// Priority makes contention-heavy background jobs yield to user traffic.
+	Priority CockroachPriority
+	// ReadOnly rejects writes in transaction.
+	ReadOnly bool
+	// AsOfSystemTime is an expression for historical reads, e.g. FollowerReadTimestamp
+	// or Staleness(time.Minute). It's inserted into query as is, so it mustn't
+	// come from user input. Such transactions are always read-only.
+	AsOfSystemTime string
+	// StatementTimeout aborts any statement in transaction that takes more time.
+	// Timeouts have millisecond precision, smaller ones are rounded up to a millisecond.
+	StatementTimeout time.Duration
+	// LockTimeout aborts any statement in transaction that waits for a lock more time.
+	LockTimeout time.Duration
+}
+
+type (
+	cockroachTxKey   struct{}
+	followerReadsKey struct{}
+)
+
+// WithCockroachTxOptions returns a copy of ctx which carries CockroachDB
+// transaction options for DB.Tx.
+func WithCockroachTxOptions(ctx context.Context, opts CockroachTxOptions) context.Context {
+	return context.WithValue(ctx, cockroachTxKey{}, opts)
+}
+
+// CockroachTxOptionsFrom returns CockroachDB transaction options carried by ctx.
+func CockroachTxOptionsFrom(ctx context.Context) CockroachTxOptions {
+	opts, _ := ctx.Value(cockroachTxKey{}).(CockroachTxOptions)
+	return opts
+}
+
+// WithFollowerReads returns a copy of ctx which enables CockroachDB follower reads:
+// - DB.Tx runs read-only transaction AS OF SYSTEM TIME follower_read_timestamp(),
+// - DB.Conn sets default_transaction_use_follower_reads for the call.
+func WithFollowerReads(ctx context.Context) context.Context {
+	opts := CockroachTxOptionsFrom(ctx)
+	opts.AsOfSystemTime = FollowerReadTimestamp
+	ctx = context.WithValue(ctx, followerReadsKey{}, true)
+	return WithCockroachTxOptions(ctx, opts)
+}
+
+// WithStaleness returns a copy of ctx which makes DB.Tx run read-only
+// CockroachDB transaction AS OF SYSTEM TIME staleness ago.
+func WithStaleness(ctx context.Context, staleness time.Duration) context.Context {
+	opts := CockroachTxOptionsFrom(ctx)
+	opts.AsOfSystemTime = Staleness(staleness)
+	return WithCockroachTxOptions(ctx, opts)
+}
+
+// Staleness returns AS OF SYSTEM TIME expression for reading data staleness ago.
+// Timestamps have microsecond precision, so smaller staleness (including
+// non-positive) is raised to a microsecond and expression is always in the past.
+func Staleness(staleness time.Duration) string {
+	if staleness < time.Microsecond {
+		staleness = time.Microsecond
+	}
+
+	return "'-" + strconv.FormatFloat(staleness.Seconds(), 'f', -1, 64) + "s'"
+}
+
+// txOptions returns options for starting transaction with settings from ctx.
+// Stale transactions are always read-only, so writes are rejected by database.
+func (db *DB) txOptions(ctx context.Context, opts *sql.TxOptions) *sql.TxOptions {
+	crdb := CockroachTxOptionsFrom(ctx)
+	if !crdb.ReadOnly && crdb.AsOfSystemTime == "" {
+		return opts
+	}
+
+	readOnly := sql.TxOptions{ReadOnly: true}
+	if opts != nil {
+		readOnly.Isolation = opts.Isolation
+	}
+
+	return &readOnly
+}
+
+// setCockroachTx applies CockroachDB options for current transaction,
+// it must be called before any other statement in transaction.
+func (db *DB) setCockroachTx(ctx context.Context, tx *sqlx.Tx) error {
+	opts := CockroachTxOptionsFrom(ctx)
+
+	if opts.AsOfSystemTime != "" {
+		_, err := tx.ExecContext(ctx, `SET TRANSACTION AS OF SYSTEM TIME `+opts.AsOfSystemTime)
+		if err != nil {
+			return fmt.Errorf("set transaction as of system time: %w", err)
+		}
+	}
+
+	if opts.Priority != 0 {
+		_, err := tx.ExecContext(ctx, `SET TRANSACTION PRIORITY `+opts.Priority.String())
+		if err != nil {
+			return fmt.Errorf("set transaction priority: %w", err)
+		}
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", opts.StatementTimeout},
+		{"lock_timeout", opts.LockTimeout},
+	}
+	for _, timeout := range timeouts {
+		switch {
+		case timeout.value == 0:
+			continue
+		case timeout.value < 0:
+			return fmt.Errorf("%w: %s %s", ErrNegativeTimeout, timeout.name, timeout.value)
+		}
+
+		// Rounded up, because 0ms disables timeout.
+		ms := (timeout.value + time.Millisecond - 1) / time.Millisecond
+		_, err := tx.ExecContext(ctx, `SELECT set_config($1, $2, true)`, timeout.name, strconv.FormatInt(int64(ms), 10)+"ms")
+		if err != nil {
+			return fmt.Errorf("set_config %s: %w", timeout.name, err)
+		}
+	}
+
+	return nil
+}
+
+// setFollowerReadsConn enables follower reads for pinned connection and returns
+// func for resetting it before connection is released.
+func (db *DB) setFollowerReadsConn(ctx context.Context, conn *sqlx.Conn) (reset func() error, err error) {
+	if enabled, _ := ctx.Value(followerReadsKey{}).(bool); !enabled {
+		return nil, nil
+	}
+
+	return setConnVariable(ctx, conn, "default_transaction_use_follower_reads", "on")
+}
@@ -0,0 +1,224 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCockroachTxOptionsFrom(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := map[string]struct {
+		given context.Context
+		want  CockroachTxOptions
+	}{
+		"none":           {ctx, CockroachTxOptions{}},
+		"follower_reads": {WithFollowerReads(ctx), CockroachTxOptions{AsOfSystemTime: "follower_read_timestamp()"}},
+		"staleness":      {WithStaleness(ctx, 10*time.Second), CockroachTxOptions{AsOfSystemTime: "'-10s'"}},
+		"staleness_ms":   {WithStaleness(ctx, 1500*time.Millisecond), CockroachTxOptions{AsOfSystemTime: "'-1.5s'"}},
+		"staleness_zero": {WithStaleness(ctx, 0), CockroachTxOptions{AsOfSystemTime: "'-0.000001s'"}},
+		"staleness_neg":  {WithStaleness(ctx, -time.Second), CockroachTxOptions{AsOfSystemTime: "'-0.000001s'"}},
+		"merged": {
+			WithFollowerReads(WithCockroachTxOptions(ctx, CockroachTxOptions{Priority: CockroachPriorityLow})),
+			CockroachTxOptions{Priority: CockroachPriorityLow, AsOfSystemTime: "follower_read_timestamp()"},
+		},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			r.Equal(tc.want, CockroachTxOptionsFrom(tc.given))
+		})
+	}
+}
+
+func TestDB_TxOptions(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	db := &DB{}
+	ctx := context.Background()
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	r.Same(opts, db.txOptions(ctx, opts))
+	r.Same(opts, db.txOptions(WithCockroachTxOptions(ctx, CockroachTxOptions{Priority: CockroachPriorityHigh}), opts))
+	r.Equal(&sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, db.txOptions(WithFollowerReads(ctx), opts))
+	r.Equal(&sql.TxOptions{ReadOnly: true}, db.txOptions(WithFollowerReads(ctx), nil))
+	r.Equal(&sql.TxOptions{ReadOnly: true}, db.txOptions(WithCockroachTxOptions(ctx, CockroachTxOptions{ReadOnly: true}), nil))
+}
+
+const crdbDriverName = "crdb-fake"
+
+var (
+	registerCRDB sync.Once
+	crdbLogs     sync.Map // DSN -> *crdbLog.
+)
+
+// crdbLog contains transactions and statements received by connections.
+type crdbLog struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (l *crdbLog) add(statement string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statements = append(l.statements, statement)
+}
+
+func (l *crdbLog) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.statements...)
+}
+
+// crdbDriver accepts any statement and logs it.
+type crdbDriver struct{}
+
+func (crdbDriver) Open(dsn string) (driver.Conn, error) {
+	l, _ := crdbLogs.LoadOrStore(dsn, &crdbLog{})
+	return crdbConn{log: l.(*crdbLog)}, nil
+}
+
+type crdbConn struct{ log *crdbLog }
+
+func (crdbConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (crdbConn) Close() error                        { return nil }
+
+func (c crdbConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c crdbConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.log.add("BEGIN READ ONLY")
+	} else {
+		c.log.add("BEGIN")
+	}
+
+	return crdbTx(c), nil
+}
+
+func (c crdbConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	for _, arg := range args {
+		query += fmt.Sprintf(" %v", arg.Value)
+	}
+	c.log.add(query)
+
+	return driver.ResultNoRows, nil
+}
+
+type crdbTx struct{ log *crdbLog }
+
+func (tx crdbTx) Commit() error   { tx.log.add("COMMIT"); return nil }
+func (tx crdbTx) Rollback() error { tx.log.add("ROLLBACK"); return nil }
+
+type crdbConnector string
+
+func (c crdbConnector) DSN() (string, error) { return string(c), nil }
+
+// newCRDB returns DB connected to crdbDriver and log of its statements.
+func newCRDB(t *testing.T, cfg Config) (*DB, *crdbLog) {
+	t.Helper()
+	r := require.New(t)
+	registerCRDB.Do(func() { sql.Register(crdbDriverName, crdbDriver{}) })
+
+	dsn := t.Name()
+	db, err := New(context.Background(), crdbDriverName, cfg, crdbConnector(dsn))
+	r.NoError(err)
+	t.Cleanup(func() {
+		r.NoError(db.Close())
+		crdbLogs.Delete(dsn)
+	})
+
+	l, _ := crdbLogs.LoadOrStore(dsn, &crdbLog{})
+	return db, l.(*crdbLog)
+}
+
+func TestDB_Tx_Cockroach(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := map[string]struct {
+		ctx  context.Context
+		want []string
+	}{
+		"none": {ctx, []string{"BEGIN", "COMMIT"}},
+		"follower_reads": {WithFollowerReads(ctx), []string{
+			"BEGIN READ ONLY",
+			"SET TRANSACTION AS OF SYSTEM TIME follower_read_timestamp()",
+			"COMMIT",
+		}},
+		"staleness": {WithStaleness(ctx, 10*time.Second), []string{
+			"BEGIN READ ONLY",
+			"SET TRANSACTION AS OF SYSTEM TIME '-10s'",
+			"COMMIT",
+		}},
+		"read_only": {WithCockroachTxOptions(ctx, CockroachTxOptions{ReadOnly: true}), []string{
+			"BEGIN READ ONLY",
+			"COMMIT",
+		}},
+		"priority": {WithCockroachTxOptions(ctx, CockroachTxOptions{Priority: CockroachPriorityLow}), []string{
+			"BEGIN",
+			"SET TRANSACTION PRIORITY LOW",
+			"COMMIT",
+		}},
+		"timeouts": {WithCockroachTxOptions(ctx, CockroachTxOptions{StatementTimeout: 1500 * time.Millisecond, LockTimeout: time.Second}), []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true) statement_timeout 1500ms",
+			"SELECT set_config($1, $2, true) lock_timeout 1000ms",
+			"COMMIT",
+		}},
+		"timeouts_sub_ms": {WithCockroachTxOptions(ctx, CockroachTxOptions{StatementTimeout: 500 * time.Microsecond, LockTimeout: time.Nanosecond}), []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true) statement_timeout 1ms",
+			"SELECT set_config($1, $2, true) lock_timeout 1ms",
+			"COMMIT",
+		}},
+		"all": {
+			WithStaleness(WithCockroachTxOptions(ctx, CockroachTxOptions{Priority: CockroachPriorityHigh, LockTimeout: time.Second}), time.Minute),
+			[]string{
+				"BEGIN READ ONLY",
+				"SET TRANSACTION AS OF SYSTEM TIME '-60s'",
+				"SET TRANSACTION PRIORITY HIGH",
+				"SELECT set_config($1, $2, true) lock_timeout 1000ms",
+				"COMMIT",
+			},
+		},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			db, log := newCRDB(t, Config{})
+			err := db.tx(tc.ctx, "Method", nil, nil, func(*sqlx.Tx) error { return nil })
+			r.NoError(err)
+			r.Equal(tc.want, log.all())
+		})
+	}
+}
+
+func TestDB_Tx_CockroachNegativeTimeout(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	db, log := newCRDB(t, Config{StrictPolicy: StrictError})
+	ctx := WithCockroachTxOptions(context.Background(), CockroachTxOptions{LockTimeout: -time.Second})
+	err := db.tx(ctx, "Method", nil, nil, func(*sqlx.Tx) error { return nil })
+	r.ErrorIs(err, ErrNegativeTimeout)
+	r.Equal([]string{"BEGIN", "ROLLBACK"}, log.all())
+}
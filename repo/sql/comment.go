@@ -0,0 +1,334 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Query tags.
+const (
+	TagMethod  = "method"
+	TagService = "service"
+)
+
+// QueryTags enables sqlcommenter-style comments, which are appended to every
+// statement, so DBA tooling can attribute load to code. For example:
+//
+//	SELECT 1 /*method='GetUser',request_id='42',service='users'*/
+//
+// Method name is added for DB.Tx, DB.Conn and statements executed with ctx
+// passed to f of DB.NoTxContext, but not for DB.NoTx, because it has no context.
+// Statements of DB.Stmt and similar methods have only Service tag, because
+// they're prepared once and shared by all callers.
+// See https://google.github.io/sqlcommenter/spec/.
+type QueryTags struct {
+	// Service is a service name.
+	Service string
+	// FromContext returns tags carried by statement's context (e.g. request or trace id).
+	FromContext func(context.Context) map[string]string
+}
+
+type (
+	methodNameKey struct{}
+	staticTagsKey struct{}
+)
+
+func withMethodName(ctx context.Context, methodName string) context.Context {
+	return context.WithValue(ctx, methodNameKey{}, methodName)
+}
+
+// withStaticTags returns a copy of ctx for statements which are shared by
+// callers, so tags of ctx and connection aren't added to them.
+func withStaticTags(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staticTagsKey{}, true)
+}
+
+func isStaticTags(ctx context.Context) bool {
+	static, _ := ctx.Value(staticTagsKey{}).(bool)
+	return static
+}
+
+// tags returns tags for statement executed with ctx.
+func (q *QueryTags) tags(ctx context.Context) map[string]string {
+	tags := make(map[string]string)
+	if isStaticTags(ctx) {
+		if q.Service != "" {
+			tags[TagService] = q.Service
+		}
+		return tags
+	}
+	if q.FromContext != nil {
+		for key, value := range q.FromContext(ctx) {
+			tags[key] = value
+		}
+	}
+	if q.Service != "" {
+		tags[TagService] = q.Service
+	}
+	if methodName, ok := ctx.Value(methodNameKey{}).(string); ok {
+		tags[TagMethod] = methodName
+	}
+
+	return tags
+}
+
+// comment returns sqlcommenter comment for tags.
+func comment(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		// PathEscape escapes quotes and slashes, so comment can't be closed by value.
+		pairs = append(pairs, url.PathEscape(key)+"='"+url.PathEscape(value)+"'")
+	}
+	sort.Strings(pairs)
+
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+var (
+	_ driver.Connector = (*dsnConnector)(nil)
+	_ driver.Connector = (*commentConnector)(nil)
+)
+
+// dsnConnector makes driver.Connector from driver which doesn't implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector.
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector.
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// newConnector returns driver.Connector for dsn, lib/pq connector is used
+// for lib/pq, because only it respects ctx of Connect.
+func newConnector(d driver.Driver, dsn string) (driver.Connector, error) {
+	if _, ok := d.(*pq.Driver); ok {
+		keepalive, err := newKeepaliveConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("newKeepaliveConnector: %w", err)
+		}
+		if keepalive != nil {
+			return keepalive, nil
+		}
+		return pq.NewConnector(dsn)
+	}
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+
+	return dsnConnector{dsn: dsn, driver: d}, nil
+}
+
+// commentConnector makes connections which add tags to every statement.
+type commentConnector struct {
+	driver.Connector
+	tags *QueryTags
+}
+
+// Connect implements driver.Connector.
+func (c *commentConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commentConn{conn: conn, tags: c.tags}, nil
+}
+
+var (
+	_ driver.Conn               = (*commentConn)(nil)
+	_ driver.ConnBeginTx        = (*commentConn)(nil)
+	_ driver.ConnPrepareContext = (*commentConn)(nil)
+	_ driver.ExecerContext      = (*commentConn)(nil)
+	_ driver.QueryerContext     = (*commentConn)(nil)
+	_ driver.Pinger             = (*commentConn)(nil)
+	_ driver.SessionResetter    = (*commentConn)(nil)
+	_ driver.Validator          = (*commentConn)(nil)
+	_ driver.NamedValueChecker  = (*commentConn)(nil)
+)
+
+// commentConn adds tags to every statement. Tags of context which was used
+// for starting transaction (or pinning connection) are added to all
+// transaction's statements.
+type commentConn struct {
+	conn driver.Conn
+	tags *QueryTags
+
+	mu      sync.Mutex
+	connCtx context.Context
+}
+
+func (c *commentConn) setConnCtx(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connCtx = ctx
+}
+
+func (c *commentConn) query(ctx context.Context, query string) string {
+	tags := c.tags.tags(ctx)
+
+	c.mu.Lock()
+	connCtx := c.connCtx
+	c.mu.Unlock()
+	if connCtx != nil && !isStaticTags(ctx) {
+		for key, value := range c.tags.tags(connCtx) {
+			if _, ok := tags[key]; !ok {
+				tags[key] = value
+			}
+		}
+	}
+
+	if tags := comment(tags); tags != "" {
+		return query + " " + tags
+	}
+
+	return query
+}
+
+// Prepare implements driver.Conn.
+func (c *commentConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(c.query(context.Background(), query))
+}
+
+// Close implements driver.Conn.
+func (c *commentConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin implements driver.Conn.
+func (c *commentConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *commentConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if conn, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = conn.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin() //nolint:staticcheck // Fallback for old drivers.
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.setConnCtx(ctx)
+	return &commentTx{Tx: tx, conn: c}, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *commentConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query = c.query(ctx, query)
+	if conn, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return conn.PrepareContext(ctx, query)
+	}
+
+	return c.conn.Prepare(query)
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *commentConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return conn.ExecContext(ctx, c.query(ctx, query), args)
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *commentConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return conn.QueryContext(ctx, c.query(ctx, query), args)
+}
+
+// Ping implements driver.Pinger.
+func (c *commentConn) Ping(ctx context.Context) error {
+	if conn, ok := c.conn.(driver.Pinger); ok {
+		return conn.Ping(ctx)
+	}
+
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *commentConn) ResetSession(ctx context.Context) error {
+	c.setConnCtx(nil)
+	if conn, ok := c.conn.(driver.SessionResetter); ok {
+		return conn.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *commentConn) IsValid() bool {
+	if conn, ok := c.conn.(driver.Validator); ok {
+		return conn.IsValid()
+	}
+
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *commentConn) CheckNamedValue(value *driver.NamedValue) error {
+	if conn, ok := c.conn.(driver.NamedValueChecker); ok {
+		return conn.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+// commentTx resets transaction's tags after transaction is finished.
+type commentTx struct {
+	driver.Tx
+	conn *commentConn
+}
+
+// Commit implements driver.Tx.
+func (tx *commentTx) Commit() error {
+	tx.conn.setConnCtx(nil)
+	return tx.Tx.Commit()
+}
+
+// Rollback implements driver.Tx.
+func (tx *commentTx) Rollback() error {
+	tx.conn.setConnCtx(nil)
+	return tx.Tx.Rollback()
+}
+
+// tagConn makes pinned connection add tags of ctx to every statement
+// until it's released.
+func tagConn(ctx context.Context, conn *sqlx.Conn) {
+	_ = conn.Raw(func(driverConn interface{}) error {
+		if c, ok := driverConn.(*commentConn); ok {
+			c.setConnCtx(ctx)
+		}
+		return nil
+	})
+}
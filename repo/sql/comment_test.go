@@ -0,0 +1,52 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type requestIDKey struct{}
+
+func TestCommentConn_Query(t *testing.T) {
+	t.Parallel()
+
+	tags := &QueryTags{
+		Service: "users",
+		FromContext: func(ctx context.Context) map[string]string {
+			if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+				return map[string]string{"request_id": id}
+			}
+			return nil
+		},
+	}
+	ctx := context.Background()
+	reqCtx := context.WithValue(ctx, requestIDKey{}, "it's 42/*")
+
+	tests := map[string]struct {
+		connCtx context.Context
+		ctx     context.Context
+		want    string
+	}{
+		"service":    {nil, ctx, `SELECT 1 /*service='users'*/`},
+		"request_id": {nil, reqCtx, `SELECT 1 /*request_id='it%27s%2042%2F%2A',service='users'*/`},
+		"tx":         {withMethodName(ctx, "GetUser"), reqCtx, `SELECT 1 /*method='GetUser',request_id='it%27s%2042%2F%2A',service='users'*/`},
+		"tx_request": {withMethodName(reqCtx, "GetUser"), ctx, `SELECT 1 /*method='GetUser',request_id='it%27s%2042%2F%2A',service='users'*/`},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			conn := &commentConn{tags: tags}
+			conn.setConnCtx(tc.connCtx)
+			r.Equal(tc.want, conn.query(tc.ctx, "SELECT 1"))
+		})
+	}
+
+	r := require.New(t)
+	r.Equal("SELECT 1", (&commentConn{tags: &QueryTags{}}).query(ctx, "SELECT 1"))
+}
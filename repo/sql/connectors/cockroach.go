@@ -17,6 +17,7 @@ var (
 	_ json.Unmarshaler         = (*CockroachSSL)(nil)
 	_ encoding.TextUnmarshaler = (*CockroachSSL)(nil)
 	_ connector.Connector      = (*CockroachDB)(nil)
+	_ connector.Validator      = (*CockroachDB)(nil)
 )
 
 // CockroachSSL is a type for setting connection ssl mode to CockroachDB.
@@ -71,6 +72,15 @@ const (
 	CockroachSSLVerifyFull              // verify-full
 )
 
+// variables returns deprecated Variable merged into Variables.
+func (o *CockroachDBOptions) variables() []CockroachDBVariable {
+	if o.Variable.Name == "" {
+		return o.Variables
+	}
+
+	return append([]CockroachDBVariable{o.Variable}, o.Variables...)
+}
+
 type (
 	// CockroachDBVariable sets variable for connections.
 	CockroachDBVariable struct {
@@ -80,8 +90,12 @@ type (
 
 	// CockroachDBOptions contains options for setting variables and cluster ID.
 	CockroachDBOptions struct {
-		Cluster  string              `yaml:"cluster" json:"cluster" hcl:"cluster"`
-		Variable CockroachDBVariable `yaml:"variable" json:"variable" hcl:"variable,block"`
+		Cluster string `yaml:"cluster" json:"cluster" hcl:"cluster"`
+		// Deprecated: Use Variables, Variable is set before them if its Name isn't empty.
+		Variable  CockroachDBVariable   `yaml:"variable" json:"variable" hcl:"variable,block"`
+		Variables []CockroachDBVariable `yaml:"variables" json:"variables" hcl:"variables,block"`
+		// Flags are other options, e.g. --results_buffer_size=16384.
+		Flags []string `yaml:"flags" json:"flags" hcl:"flags"`
 	}
 
 	// CockroachDBParameters contains url parameters for connecting to database.
@@ -91,6 +105,9 @@ type (
 		SSLRootCert     string       `yaml:"ssl_root_cert" json:"ssl_root_cert" hcl:"ssl_root_cert"`
 		SSLCert         string       `yaml:"ssl_cert" json:"ssl_cert" hcl:"ssl_cert"`
 		SSLKey          string       `yaml:"ssl_key" json:"ssl_key" hcl:"ssl_key"`
+		// TargetSessionAttrs is one of any, read-write, read-only, primary,
+		// standby or prefer-standby, it's used for choosing one of CockroachDB.Hosts.
+		TargetSessionAttrs string `yaml:"target_session_attrs" json:"target_session_attrs" hcl:"target_session_attrs"`
 
 		// It isn't recommended, so it's disable. You must use CockroachDB.Password instead of it.
 		// Password        string
@@ -99,39 +116,83 @@ type (
 	}
 
 	// CockroachDB config for connecting to cockroachDB.
+	// Hosts is a list of host[:port] endpoints which is used instead of
+	// Host for failover, Port is used for endpoints without port.
+	// DirectoryPath is a directory of UNIX domain socket which is used
+	// instead of Host, Port is used in socket file name.
 	CockroachDB struct {
 		User       string                 `yaml:"user" json:"user" hcl:"user"`
 		Password   string                 `yaml:"password" json:"password" hcl:"password"`
 		Host       string                 `yaml:"host" json:"host" hcl:"host"`
 		Port       int                    `yaml:"port" json:"port" hcl:"port"`
+		Hosts      []string               `yaml:"hosts" json:"hosts" hcl:"hosts"`
 		Database   string                 `yaml:"database" json:"database" hcl:"database"`
 		Parameters *CockroachDBParameters `yaml:"parameters" json:"parameters" hcl:"parameters,block"`
 
-		// We don't have support for UNIX domain socket.
-		// DirectoryPath string `yaml:"directory_path" json:"directory_path"`
+		DirectoryPath string `yaml:"directory_path" json:"directory_path" hcl:"directory_path"`
 	}
 )
 
+// Validate returns ValidationError with all problems of config which
+// can't be used for connecting.
+func (c CockroachDB) Validate() error {
+	v := &validator{}
+	v.check(c.User != "", "user", "is required")
+	v.hosts(c.Host, c.Hosts, c.DirectoryPath)
+	v.port("port", c.Port)
+	if c.Parameters == nil {
+		return v.err()
+	}
+
+	p := c.Parameters
+	v.check(p.TargetSessionAttrs == "" || contains(targetSessionAttrs, p.TargetSessionAttrs),
+		"parameters.target_session_attrs", "unknown value %s", p.TargetSessionAttrs)
+	verify := p.Mode == CockroachSSLVerifyCa || p.Mode == CockroachSSLVerifyFull
+	v.check(!verify || p.SSLRootCert != "", "parameters.ssl_root_cert", "is required for %s mode", p.Mode)
+	v.check((p.SSLCert == "") == (p.SSLKey == ""), "parameters.ssl_key", "must be set together with ssl_cert")
+	if p.Mode != CockroachSSLDisable {
+		v.file("parameters.ssl_root_cert", p.SSLRootCert)
+		v.file("parameters.ssl_cert", p.SSLCert)
+		v.file("parameters.ssl_key", p.SSLKey)
+	}
+	if p.Options == nil {
+		return v.err()
+	}
+
+	for i := range p.Options.Variables {
+		v.check(p.Options.Variables[i].Name != "", fmt.Sprintf("parameters.options.variables[%d].name", i), "is required")
+	}
+	for i := range p.Options.Flags {
+		v.check(strings.HasPrefix(p.Options.Flags[i], "-"), fmt.Sprintf("parameters.options.flags[%d]", i), "must start with -")
+	}
+
+	return v.err()
+}
+
 // DSN convert struct to DSN and returns connection string.
 func (c CockroachDB) DSN() (string, error) {
-	str := fmt.Sprintf("postgres://%s:%s@%s:%d/%s",
-		c.User,
-		c.Password,
-		c.Host,
-		c.Port,
-		c.Database,
-	)
-
-	uri, err := url.Parse(str)
-	if err != nil {
-		return "", fmt.Errorf("url.Parse: %w", err)
+	hosts := c.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{c.Host}
+	}
+
+	uri := &url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   joinHosts(hosts, c.Port),
+		Path:   "/" + c.Database,
+	}
+
+	parameters := url.Values{}
+	if c.DirectoryPath != "" {
+		parameters.Add("host", c.DirectoryPath)
 	}
 
 	if c.Parameters == nil {
+		uri.RawQuery = parameters.Encode()
 		return uri.String(), nil
 	}
 
-	parameters := url.Values{}
 	if c.Parameters.ApplicationName != "" {
 		parameters.Add("application_name", c.Parameters.ApplicationName)
 	}
@@ -152,6 +213,10 @@ func (c CockroachDB) DSN() (string, error) {
 		parameters.Add("sslkey", c.Parameters.SSLKey)
 	}
 
+	if c.Parameters.TargetSessionAttrs != "" {
+		parameters.Add("target_session_attrs", c.Parameters.TargetSessionAttrs)
+	}
+
 	uri.RawQuery = parameters.Encode()
 	if c.Parameters.Options == nil {
 		return uri.String(), nil
@@ -159,11 +224,15 @@ func (c CockroachDB) DSN() (string, error) {
 
 	var options []string
 	if c.Parameters.Options.Cluster != "" {
-		options = append(options, fmt.Sprintf("--cluster=%s", c.Parameters.Options.Cluster))
+		options = append(options, escapeOption("--cluster="+c.Parameters.Options.Cluster))
 	}
 
-	if c.Parameters.Options.Variable.Name != "" {
-		options = append(options, fmt.Sprintf("-c %s=%s", c.Parameters.Options.Variable.Name, c.Parameters.Options.Variable.Value))
+	for _, v := range c.Parameters.Options.variables() {
+		options = append(options, optionVariable(v.Name, v.Value))
+	}
+
+	for _, flag := range c.Parameters.Options.Flags {
+		options = append(options, escapeOption(flag))
 	}
 
 	parameters.Add("options", strings.Join(options, " "))
@@ -171,3 +240,69 @@ func (c CockroachDB) DSN() (string, error) {
 
 	return uri.String(), nil
 }
+
+// ParseCockroachDB converts DSN returned by CockroachDB.DSN back to config.
+func ParseCockroachDB(dsn string) (CockroachDB, error) {
+	uri, query, err := parsePostgresURL(dsn)
+	if err != nil {
+		return CockroachDB{}, err
+	}
+
+	c := CockroachDB{Database: strings.TrimPrefix(uri.Path, "/")}
+	if uri.User != nil {
+		c.User = uri.User.Username()
+		c.Password, _ = uri.User.Password()
+	}
+	c.Host, c.Port, c.Hosts, err = parseHosts(uri.Host)
+	if err != nil {
+		return CockroachDB{}, err
+	}
+	c.DirectoryPath = popParameter(query, "host")
+	if len(query) == 0 {
+		return c, nil
+	}
+
+	p := &CockroachDBParameters{
+		ApplicationName:    popParameter(query, "application_name"),
+		SSLRootCert:        popParameter(query, "sslrootcert"),
+		SSLCert:            popParameter(query, "sslcert"),
+		SSLKey:             popParameter(query, "sslkey"),
+		TargetSessionAttrs: popParameter(query, "target_session_attrs"),
+	}
+	if mode := popParameter(query, "sslmode"); mode != "" {
+		err = p.Mode.UnmarshalText([]byte(mode))
+		if err != nil {
+			return CockroachDB{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+		}
+	}
+	if options := popParameter(query, "options"); options != "" {
+		p.Options = parseCockroachDBOptions(options)
+	}
+
+	err = unknownParameters(query)
+	if err != nil {
+		return CockroachDB{}, err
+	}
+	c.Parameters = p
+
+	return c, nil
+}
+
+func parseCockroachDBOptions(options string) *CockroachDBOptions {
+	o := &CockroachDBOptions{}
+	args := splitOptions(options)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.HasPrefix(args[i], "--cluster="):
+			o.Cluster = strings.TrimPrefix(args[i], "--cluster=")
+		case args[i] == "-c" && i+1 < len(args):
+			i++
+			name, value := splitVariable(args[i])
+			o.Variables = append(o.Variables, CockroachDBVariable{Name: name, Value: value})
+		default:
+			o.Flags = append(o.Flags, args[i])
+		}
+	}
+
+	return o
+}
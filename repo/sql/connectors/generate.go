@@ -1,3 +1,8 @@
 package connectors
 
 //go:generate stringer -type=CockroachSSL -linecomment
+//go:generate stringer -type=PostgresSSL -linecomment
+//go:generate stringer -type=MySQLTLS -linecomment
+//go:generate stringer -type=SQLiteJournalMode -linecomment
+//go:generate stringer -type=SQLiteSynchronous -linecomment
+//go:generate stringer -type=SQLiteCache -linecomment
@@ -0,0 +1,241 @@
+package connectors
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+
+	connector "github.com/Meat-Hook/framework/repo/sql"
+)
+
+var (
+	_ yaml.Unmarshaler         = (*MySQLTLS)(nil)
+	_ json.Unmarshaler         = (*MySQLTLS)(nil)
+	_ encoding.TextUnmarshaler = (*MySQLTLS)(nil)
+	_ connector.Connector      = (*MySQL)(nil)
+	_ connector.Validator      = (*MySQL)(nil)
+)
+
+//nolint:gochecknoinits // Like other packages which add support for drivers.
+func init() {
+	connector.RegisterDriverError(func(err error) bool {
+		return errors.As(err, new(*mysql.MySQLError)) || errors.Is(err, mysql.ErrInvalidConn)
+	})
+}
+
+// MySQLTLS is a type for setting connection TLS mode to MySQL/MariaDB.
+type MySQLTLS uint8
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *MySQLTLS) UnmarshalJSON(b []byte) error {
+	str := ""
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return i.UnmarshalText([]byte(str))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (i *MySQLTLS) UnmarshalYAML(b *yaml.Node) error {
+	return i.UnmarshalText([]byte(b.Value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *MySQLTLS) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case MySQLTLSDisable.String():
+		*i = MySQLTLSDisable
+	case MySQLTLSRequire.String():
+		*i = MySQLTLSRequire
+	case MySQLTLSSkipVerify.String():
+		*i = MySQLTLSSkipVerify
+	case MySQLTLSPreferred.String():
+		*i = MySQLTLSPreferred
+	default:
+		return fmt.Errorf("unknown mode: %s", str)
+	}
+
+	return nil
+}
+
+// Enum.
+const (
+	_                  MySQLTLS = iota
+	MySQLTLSDisable             // false
+	MySQLTLSRequire             // true
+	MySQLTLSSkipVerify          // skip-verify
+	MySQLTLSPreferred           // preferred
+)
+
+// DefaultMySQLPort is used if MySQL.Port isn't set.
+const DefaultMySQLPort = 3306
+
+type (
+	// MySQLParameters contains parameters for connecting to database,
+	// see https://github.com/go-sql-driver/mysql#parameters.
+	MySQLParameters struct {
+		ParseTime bool `yaml:"parse_time" json:"parse_time" hcl:"parse_time"`
+		// Loc is a time zone name, e.g. UTC or Local.
+		Loc       string   `yaml:"loc" json:"loc" hcl:"loc"`
+		Charset   string   `yaml:"charset" json:"charset" hcl:"charset"`
+		Collation string   `yaml:"collation" json:"collation" hcl:"collation"`
+		Mode      MySQLTLS `yaml:"mode" json:"mode" hcl:"mode"`
+		// TLSConfigName is a name of config registered by mysql.RegisterTLSConfig,
+		// it's used instead of Mode.
+		TLSConfigName string `yaml:"tls_config_name" json:"tls_config_name" hcl:"tls_config_name"`
+		// Timeout, ReadTimeout and WriteTimeout in milliseconds.
+		Timeout           int  `yaml:"timeout" json:"timeout" hcl:"timeout"`
+		ReadTimeout       int  `yaml:"read_timeout" json:"read_timeout" hcl:"read_timeout"`
+		WriteTimeout      int  `yaml:"write_timeout" json:"write_timeout" hcl:"write_timeout"`
+		InterpolateParams bool `yaml:"interpolate_params" json:"interpolate_params" hcl:"interpolate_params"`
+	}
+
+	// MySQL config for connecting to MySQL/MariaDB.
+	MySQL struct {
+		User       string           `yaml:"user" json:"user" hcl:"user"`
+		Password   string           `yaml:"password" json:"password" hcl:"password"`
+		Host       string           `yaml:"host" json:"host" hcl:"host"`
+		Port       int              `yaml:"port" json:"port" hcl:"port"`
+		Database   string           `yaml:"database" json:"database" hcl:"database"`
+		Parameters *MySQLParameters `yaml:"parameters" json:"parameters" hcl:"parameters,block"`
+	}
+)
+
+// Validate returns ValidationError with all problems of config which
+// can't be used for connecting.
+func (c MySQL) Validate() error {
+	v := &validator{}
+	v.check(c.User != "", "user", "is required")
+	v.check(c.Host != "", "host", "is required")
+	v.port("port", c.Port)
+	if c.Parameters == nil {
+		return v.err()
+	}
+
+	p := c.Parameters
+	if p.Loc != "" {
+		_, err := time.LoadLocation(p.Loc)
+		v.check(err == nil, "parameters.loc", "unknown time zone %s", p.Loc)
+	}
+	v.nonNegative("parameters.timeout", p.Timeout)
+	v.nonNegative("parameters.read_timeout", p.ReadTimeout)
+	v.nonNegative("parameters.write_timeout", p.WriteTimeout)
+
+	return v.err()
+}
+
+// DSN convert struct to DSN and returns connection string.
+func (c MySQL) DSN() (string, error) {
+	port := c.Port
+	if port == 0 {
+		port = DefaultMySQLPort
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(port))
+	cfg.DBName = c.Database
+
+	if c.Parameters == nil {
+		return cfg.FormatDSN(), nil
+	}
+
+	p := c.Parameters
+	cfg.ParseTime = p.ParseTime
+	if p.Loc != "" {
+		loc, err := time.LoadLocation(p.Loc)
+		if err != nil {
+			return "", fmt.Errorf("time.LoadLocation: %w", err)
+		}
+		cfg.Loc = loc
+	}
+	if p.Charset != "" {
+		cfg.Params = map[string]string{"charset": p.Charset}
+	}
+	if p.Collation != "" {
+		cfg.Collation = p.Collation
+	}
+	switch {
+	case p.TLSConfigName != "":
+		cfg.TLSConfig = p.TLSConfigName
+	case p.Mode != 0:
+		cfg.TLSConfig = p.Mode.String()
+	}
+	cfg.Timeout = time.Duration(p.Timeout) * time.Millisecond
+	cfg.ReadTimeout = time.Duration(p.ReadTimeout) * time.Millisecond
+	cfg.WriteTimeout = time.Duration(p.WriteTimeout) * time.Millisecond
+	cfg.InterpolateParams = p.InterpolateParams
+
+	return cfg.FormatDSN(), nil
+}
+
+// ParseMySQL converts DSN returned by MySQL.DSN back to config.
+func ParseMySQL(dsn string) (MySQL, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return MySQL{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+	if cfg.Net != "tcp" {
+		return MySQL{}, fmt.Errorf("%w: unsupported network %s", ErrInvalidDSN, cfg.Net)
+	}
+
+	host, rawPort, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return MySQL{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return MySQL{}, fmt.Errorf("%w: invalid port %q", ErrInvalidDSN, rawPort)
+	}
+
+	c := MySQL{
+		User:     cfg.User,
+		Password: cfg.Passwd,
+		Host:     host,
+		Port:     port,
+		Database: cfg.DBName,
+	}
+
+	p := &MySQLParameters{
+		ParseTime:         cfg.ParseTime,
+		Charset:           cfg.Params["charset"],
+		Timeout:           int(cfg.Timeout / time.Millisecond),
+		ReadTimeout:       int(cfg.ReadTimeout / time.Millisecond),
+		WriteTimeout:      int(cfg.WriteTimeout / time.Millisecond),
+		InterpolateParams: cfg.InterpolateParams,
+	}
+	if cfg.Loc != time.UTC {
+		p.Loc = cfg.Loc.String()
+	}
+	if cfg.Collation != mysql.NewConfig().Collation {
+		p.Collation = cfg.Collation
+	}
+	if cfg.TLSConfig != "" && p.Mode.UnmarshalText([]byte(cfg.TLSConfig)) != nil {
+		p.TLSConfigName = cfg.TLSConfig
+	}
+	if *p != (MySQLParameters{}) {
+		c.Parameters = p
+	}
+
+	// Parameters which aren't supported by MySQL are lost, so DSN differs.
+	roundTrip, err := c.DSN()
+	if err != nil {
+		return MySQL{}, fmt.Errorf("c.DSN: %w", err)
+	}
+	if roundTrip != cfg.FormatDSN() {
+		return MySQL{}, fmt.Errorf("%w: unsupported parameters in %s", ErrInvalidDSN, cfg.FormatDSN())
+	}
+
+	return c, nil
+}
@@ -0,0 +1,181 @@
+package connectors_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Meat-Hook/framework/repo/sql/connectors"
+)
+
+func mysqlAll() connectors.MySQL {
+	return connectors.MySQL{
+		User:     "user",
+		Password: "password",
+		Host:     "127.0.0.1",
+		Port:     3306,
+		Database: "app",
+		Parameters: &connectors.MySQLParameters{
+			ParseTime:         true,
+			Loc:               "UTC",
+			Charset:           "utf8mb4",
+			Collation:         "utf8mb4_unicode_ci",
+			Mode:              connectors.MySQLTLSSkipVerify,
+			Timeout:           5000,
+			ReadTimeout:       1000,
+			WriteTimeout:      2000,
+			InterpolateParams: true,
+		},
+	}
+}
+
+func TestMySQL_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path    string
+		decoder func([]byte, interface{}) error
+	}{
+		"json": {"testdata/mysql.json", func(b []byte, i interface{}) error { return json.Unmarshal(b, i) }},
+		"yaml": {"testdata/mysql.yaml", func(b []byte, i interface{}) error { return yaml.Unmarshal(b, i) }},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			b, err := os.ReadFile(tc.path)
+			r.NoError(err)
+			value := connectors.MySQL{}
+			err = tc.decoder(b, &value)
+			r.NoError(err)
+			r.Equal(mysqlAll(), value)
+		})
+	}
+}
+
+func TestMySQL_DSN(t *testing.T) {
+	t.Parallel()
+
+	// ParseDSN accepts only registered TLS config names.
+	err := mysql.RegisterTLSConfig("custom", &tls.Config{}) //nolint:gosec // Not used for connecting.
+	require.NoError(t, err)
+
+	type T = connectors.MySQL
+	change := func(fn func(*T)) T {
+		t := mysqlAll()
+		fn(&t)
+		return t
+	}
+
+	testCases := map[string]struct {
+		cfg     T
+		exp     string
+		wantErr bool
+	}{
+		"all": {mysqlAll(), "user:password@tcp(127.0.0.1:3306)/app?collation=utf8mb4_unicode_ci&interpolateParams=true&parseTime=true&readTimeout=1s&timeout=5s&tls=skip-verify&writeTimeout=2s&charset=utf8mb4", false},
+		"loc": {change(func(t *T) { t.Parameters.Loc = "Europe/Berlin" }),
+			"user:password@tcp(127.0.0.1:3306)/app?collation=utf8mb4_unicode_ci&interpolateParams=true&loc=Europe%2FBerlin&parseTime=true&readTimeout=1s&timeout=5s&tls=skip-verify&writeTimeout=2s&charset=utf8mb4", false},
+		"tls_config_name": {change(func(t *T) {
+			t.Parameters = &connectors.MySQLParameters{Mode: connectors.MySQLTLSRequire, TLSConfigName: "custom"}
+		}),
+			"user:password@tcp(127.0.0.1:3306)/app?tls=custom", false},
+		"tls_disable": {change(func(t *T) { t.Parameters = &connectors.MySQLParameters{Mode: connectors.MySQLTLSDisable} }),
+			"user:password@tcp(127.0.0.1:3306)/app?tls=false", false},
+		"without_parameters": {change(func(t *T) { t.Parameters = nil }), "user:password@tcp(127.0.0.1:3306)/app", false},
+		"default_port":       {change(func(t *T) { t.Parameters = nil; t.Port = 0 }), "user:password@tcp(127.0.0.1:3306)/app", false},
+		"ipv6":               {change(func(t *T) { t.Parameters = nil; t.Host = "::1" }), "user:password@tcp([::1]:3306)/app", false},
+		"unknown_loc":        {change(func(t *T) { t.Parameters.Loc = "Unknown/Zone" }), "", true},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			dsn, err := tc.cfg.DSN()
+			if tc.wantErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.exp, dsn)
+
+			parsed, err := connectors.ParseMySQL(dsn)
+			r.NoError(err)
+			dsn, err = parsed.DSN()
+			r.NoError(err)
+			r.Equal(tc.exp, dsn)
+		})
+	}
+}
+
+func TestMySQL_Validate(t *testing.T) {
+	t.Parallel()
+
+	type T = connectors.MySQL
+	change := func(fn func(*T)) T {
+		t := mysqlAll()
+		fn(&t)
+		return t
+	}
+
+	testCases := map[string]struct {
+		cfg  T
+		want []string
+	}{
+		"all":          {mysqlAll(), nil},
+		"empty":        {T{}, []string{"user", "host"}},
+		"port":         {change(func(t *T) { t.Port = -1 }), []string{"port"}},
+		"unknown_loc":  {change(func(t *T) { t.Parameters.Loc = "Unknown/Zone" }), []string{"parameters.loc"}},
+		"timeouts":     {change(func(t *T) { t.Parameters.Timeout = -1; t.Parameters.WriteTimeout = -1 }), []string{"parameters.timeout", "parameters.write_timeout"}},
+		"read_timeout": {change(func(t *T) { t.Parameters.ReadTimeout = -1 }), []string{"parameters.read_timeout"}},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			requireFieldErrors(t, tc.cfg.Validate(), tc.want...)
+		})
+	}
+}
+
+func TestParseMySQL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		dsn     string
+		want    connectors.MySQL
+		wantErr error
+	}{
+		"all": {"user:password@tcp(127.0.0.1:3306)/app?collation=utf8mb4_unicode_ci&interpolateParams=true&parseTime=true&readTimeout=1s&timeout=5s&tls=skip-verify&writeTimeout=2s&charset=utf8mb4",
+			func() connectors.MySQL { c := mysqlAll(); c.Parameters.Loc = ""; return c }(), nil},
+		"without_parameters": {"user:password@tcp(127.0.0.1:3306)/app",
+			connectors.MySQL{User: "user", Password: "password", Host: "127.0.0.1", Port: 3306, Database: "app"}, nil},
+		"unix":          {"user:password@unix(/tmp/mysql.sock)/app", connectors.MySQL{}, connectors.ErrInvalidDSN},
+		"unknown_param": {"user:password@tcp(127.0.0.1:3306)/app?rejectReadOnly=true", connectors.MySQL{}, connectors.ErrInvalidDSN},
+		"invalid":       {"user:password@tcp(127.0.0.1:3306)", connectors.MySQL{}, connectors.ErrInvalidDSN},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			c, err := connectors.ParseMySQL(tc.dsn)
+			r.ErrorIs(err, tc.wantErr)
+			r.Equal(tc.want, c)
+		})
+	}
+}
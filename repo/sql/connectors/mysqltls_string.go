@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=MySQLTLS -linecomment"; DO NOT EDIT.
+
+package connectors
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MySQLTLSDisable-1]
+	_ = x[MySQLTLSRequire-2]
+	_ = x[MySQLTLSSkipVerify-3]
+	_ = x[MySQLTLSPreferred-4]
+}
+
+const _MySQLTLS_name = "falsetrueskip-verifypreferred"
+
+var _MySQLTLS_index = [...]uint8{0, 5, 9, 20, 29}
+
+func (i MySQLTLS) String() string {
+	i -= 1
+	if i >= MySQLTLS(len(_MySQLTLS_index)-1) {
+		return "MySQLTLS(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _MySQLTLS_name[_MySQLTLS_index[i]:_MySQLTLS_index[i+1]]
+}
@@ -0,0 +1,154 @@
+package connectors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	dsnutil "github.com/Meat-Hook/framework/repo/sql/internal/dsn"
+)
+
+// ErrInvalidDSN is returned by Parse functions for DSN which can't be
+// converted to connector.
+var ErrInvalidDSN = errors.New("invalid DSN")
+
+// parsePostgresURL parses DSN in URL format of PostgreSQL compatible database.
+func parsePostgresURL(dsn string) (*url.URL, url.Values, error) {
+	dsn, hosts := dsnutil.CutHosts(dsn)
+	uri, err := url.Parse(dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+	uri.Host = hosts
+	if uri.Scheme != "postgres" && uri.Scheme != "postgresql" {
+		return nil, nil, fmt.Errorf("%w: unknown scheme %q", ErrInvalidDSN, uri.Scheme)
+	}
+
+	query, err := url.ParseQuery(uri.RawQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+
+	return uri, query, nil
+}
+
+// parseHosts returns values of Host, Port and Hosts fields for host of URL.
+func parseHosts(uriHost string) (host string, port int, hosts []string, err error) {
+	if strings.Contains(uriHost, ",") {
+		return "", 0, strings.Split(uriHost, ","), nil
+	}
+
+	host, rawPort, err := net.SplitHostPort(uriHost)
+	if err != nil {
+		return uriHost, 0, nil, nil //nolint:nilerr // Host without port.
+	}
+	if host == "" && rawPort == "" {
+		return "", 0, nil, nil
+	}
+
+	port, err = strconv.Atoi(rawPort)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("%w: invalid port %q", ErrInvalidDSN, rawPort)
+	}
+
+	return host, port, nil, nil
+}
+
+// popParameter removes parameter from query and returns its value.
+func popParameter(query url.Values, key string) string {
+	value := query.Get(key)
+	query.Del(key)
+
+	return value
+}
+
+// popInt removes integer parameter from query and returns its value.
+func popInt(query url.Values, key string) (int, error) {
+	value := popParameter(query, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid %s %q", ErrInvalidDSN, key, value)
+	}
+
+	return i, nil
+}
+
+// popBool removes boolean parameter from query and returns its value,
+// nil is returned if parameter isn't set.
+func popBool(query url.Values, key string) (*bool, error) {
+	value := popParameter(query, key)
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s %q", ErrInvalidDSN, key, value)
+	}
+
+	return &b, nil
+}
+
+// unknownParameters returns error if query contains parameters which
+// weren't removed by popParameter.
+func unknownParameters(query url.Values) error {
+	if len(query) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return fmt.Errorf("%w: unknown parameters %s", ErrInvalidDSN, strings.Join(keys, ", "))
+}
+
+// splitOptions splits options parameter into arguments, it's inverse of escapeOption.
+func splitOptions(options string) []string {
+	var (
+		args    []string
+		arg     strings.Builder
+		escaped bool
+	)
+	for _, r := range options {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ' ':
+			if arg.Len() != 0 {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+		default:
+			arg.WriteRune(r)
+		}
+	}
+	if arg.Len() != 0 {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// splitVariable returns name and value of -c name=value argument.
+func splitVariable(arg string) (name, value string) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
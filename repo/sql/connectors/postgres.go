@@ -0,0 +1,380 @@
+package connectors
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	connector "github.com/Meat-Hook/framework/repo/sql"
+)
+
+var (
+	_ yaml.Unmarshaler         = (*PostgresSSL)(nil)
+	_ json.Unmarshaler         = (*PostgresSSL)(nil)
+	_ encoding.TextUnmarshaler = (*PostgresSSL)(nil)
+	_ connector.Connector      = (*Postgres)(nil)
+	_ connector.Validator      = (*Postgres)(nil)
+)
+
+// PostgresSSL is a type for setting connection ssl mode to PostgreSQL.
+type PostgresSSL uint8
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *PostgresSSL) UnmarshalJSON(b []byte) error {
+	str := ""
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return i.UnmarshalText([]byte(str))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (i *PostgresSSL) UnmarshalYAML(b *yaml.Node) error {
+	return i.UnmarshalText([]byte(b.Value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *PostgresSSL) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case PostgresSSLDisable.String():
+		*i = PostgresSSLDisable
+	case PostgresSSLAllow.String():
+		*i = PostgresSSLAllow
+	case PostgresSSLPrefer.String():
+		*i = PostgresSSLPrefer
+	case PostgresSSLRequire.String():
+		*i = PostgresSSLRequire
+	case PostgresSSLVerifyCa.String():
+		*i = PostgresSSLVerifyCa
+	case PostgresSSLVerifyFull.String():
+		*i = PostgresSSLVerifyFull
+	default:
+		return fmt.Errorf("unknown mode: %s", str)
+	}
+
+	return nil
+}
+
+// Enum.
+const (
+	_                     PostgresSSL = iota
+	PostgresSSLDisable                // disable
+	PostgresSSLAllow                  // allow
+	PostgresSSLPrefer                 // prefer
+	PostgresSSLRequire                // require
+	PostgresSSLVerifyCa               // verify-ca
+	PostgresSSLVerifyFull             // verify-full
+)
+
+// Values of PostgresParameters.TargetSessionAttrs.
+var targetSessionAttrs = []string{"any", "read-write", "read-only", "primary", "standby", "prefer-standby"}
+
+type (
+	// PostgresVariable sets run-time parameter for connections.
+	PostgresVariable struct {
+		Name  string `yaml:"name" json:"name" hcl:"name"`
+		Value string `yaml:"value" json:"value" hcl:"value"`
+	}
+
+	// PostgresParameters contains url parameters for connecting to database,
+	// see https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-PARAMKEYWORDS.
+	// Parameters which lib/pq doesn't support are implemented by DB:
+	// target_session_attrs for multiple hosts and keepalives by dialer of
+	// connection (idle time is used as interval, keepalives_count uses OS
+	// default). sslsni isn't sent, lib/pq sends SNI only in verify-full mode.
+	PostgresParameters struct {
+		ApplicationName         string `yaml:"application_name" json:"application_name" hcl:"application_name"`
+		FallbackApplicationName string `yaml:"fallback_application_name" json:"fallback_application_name" hcl:"fallback_application_name"`
+		// ConnectTimeout in seconds.
+		ConnectTimeout     int    `yaml:"connect_timeout" json:"connect_timeout" hcl:"connect_timeout"`
+		TargetSessionAttrs string `yaml:"target_session_attrs" json:"target_session_attrs" hcl:"target_session_attrs"`
+
+		Keepalives *bool `yaml:"keepalives" json:"keepalives" hcl:"keepalives"`
+		// KeepalivesIdle, KeepalivesInterval in seconds.
+		KeepalivesIdle     int `yaml:"keepalives_idle" json:"keepalives_idle" hcl:"keepalives_idle"`
+		KeepalivesInterval int `yaml:"keepalives_interval" json:"keepalives_interval" hcl:"keepalives_interval"`
+		KeepalivesCount    int `yaml:"keepalives_count" json:"keepalives_count" hcl:"keepalives_count"`
+
+		Mode        PostgresSSL `yaml:"mode" json:"mode" hcl:"mode"`
+		SSLRootCert string      `yaml:"ssl_root_cert" json:"ssl_root_cert" hcl:"ssl_root_cert"`
+		SSLCert     string      `yaml:"ssl_cert" json:"ssl_cert" hcl:"ssl_cert"`
+		SSLKey      string      `yaml:"ssl_key" json:"ssl_key" hcl:"ssl_key"`
+		SSLSNI      *bool       `yaml:"ssl_sni" json:"ssl_sni" hcl:"ssl_sni"`
+
+		// SearchPath, StatementTimeout (in milliseconds) and Variables
+		// are passed by options as -c name=value.
+		SearchPath       string             `yaml:"search_path" json:"search_path" hcl:"search_path"`
+		StatementTimeout int                `yaml:"statement_timeout" json:"statement_timeout" hcl:"statement_timeout"`
+		Variables        []PostgresVariable `yaml:"variables" json:"variables" hcl:"variables,block"`
+	}
+
+	// Postgres config for connecting to PostgreSQL.
+	// Hosts is a list of host[:port] endpoints which is used instead of
+	// Host for failover, Port is used for endpoints without port.
+	// DirectoryPath is a directory of UNIX domain socket which is used
+	// instead of Host, Port is used in socket file name.
+	Postgres struct {
+		User       string              `yaml:"user" json:"user" hcl:"user"`
+		Password   string              `yaml:"password" json:"password" hcl:"password"`
+		Host       string              `yaml:"host" json:"host" hcl:"host"`
+		Port       int                 `yaml:"port" json:"port" hcl:"port"`
+		Hosts      []string            `yaml:"hosts" json:"hosts" hcl:"hosts"`
+		Database   string              `yaml:"database" json:"database" hcl:"database"`
+		Parameters *PostgresParameters `yaml:"parameters" json:"parameters" hcl:"parameters,block"`
+
+		DirectoryPath string `yaml:"directory_path" json:"directory_path" hcl:"directory_path"`
+	}
+)
+
+// Validate returns ValidationError with all problems of config which
+// can't be used for connecting.
+func (c Postgres) Validate() error {
+	v := &validator{}
+	v.hosts(c.Host, c.Hosts, c.DirectoryPath)
+	v.port("port", c.Port)
+	if c.Parameters == nil {
+		return v.err()
+	}
+
+	p := c.Parameters
+	v.nonNegative("parameters.connect_timeout", p.ConnectTimeout)
+	v.nonNegative("parameters.keepalives_idle", p.KeepalivesIdle)
+	v.nonNegative("parameters.keepalives_interval", p.KeepalivesInterval)
+	v.nonNegative("parameters.keepalives_count", p.KeepalivesCount)
+	v.nonNegative("parameters.statement_timeout", p.StatementTimeout)
+	v.check(p.TargetSessionAttrs == "" || contains(targetSessionAttrs, p.TargetSessionAttrs),
+		"parameters.target_session_attrs", "unknown value %s", p.TargetSessionAttrs)
+	verify := p.Mode == PostgresSSLVerifyCa || p.Mode == PostgresSSLVerifyFull
+	v.check(!verify || p.SSLRootCert != "", "parameters.ssl_root_cert", "is required for %s mode", p.Mode)
+	v.check((p.SSLCert == "") == (p.SSLKey == ""), "parameters.ssl_key", "must be set together with ssl_cert")
+	if p.Mode != PostgresSSLDisable {
+		v.file("parameters.ssl_root_cert", p.SSLRootCert)
+		v.file("parameters.ssl_cert", p.SSLCert)
+		v.file("parameters.ssl_key", p.SSLKey)
+	}
+	for i := range p.Variables {
+		v.check(p.Variables[i].Name != "", fmt.Sprintf("parameters.variables[%d].name", i), "is required")
+	}
+
+	return v.err()
+}
+
+// DSN convert struct to DSN and returns connection string.
+func (c Postgres) DSN() (string, error) {
+	hosts := c.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{c.Host}
+	}
+
+	uri := &url.URL{
+		Scheme: "postgres",
+		Host:   joinHosts(hosts, c.Port),
+		Path:   "/" + c.Database,
+	}
+	if c.User != "" {
+		uri.User = url.UserPassword(c.User, c.Password)
+	}
+
+	parameters := url.Values{}
+	if c.DirectoryPath != "" {
+		parameters.Add("host", c.DirectoryPath)
+	}
+
+	if c.Parameters == nil {
+		uri.RawQuery = parameters.Encode()
+		return uri.String(), nil
+	}
+
+	p := c.Parameters
+	addString := func(key, value string) {
+		if value != "" {
+			parameters.Add(key, value)
+		}
+	}
+	addInt := func(key string, value int) {
+		if value != 0 {
+			parameters.Add(key, strconv.Itoa(value))
+		}
+	}
+	addBool := func(key string, value *bool) {
+		switch {
+		case value == nil:
+		case *value:
+			parameters.Add(key, "1")
+		default:
+			parameters.Add(key, "0")
+		}
+	}
+
+	addString("application_name", p.ApplicationName)
+	addString("fallback_application_name", p.FallbackApplicationName)
+	addInt("connect_timeout", p.ConnectTimeout)
+	addString("target_session_attrs", p.TargetSessionAttrs)
+	addBool("keepalives", p.Keepalives)
+	addInt("keepalives_idle", p.KeepalivesIdle)
+	addInt("keepalives_interval", p.KeepalivesInterval)
+	addInt("keepalives_count", p.KeepalivesCount)
+	if p.Mode != 0 {
+		parameters.Add("sslmode", p.Mode.String())
+	}
+	addString("sslrootcert", p.SSLRootCert)
+	addString("sslcert", p.SSLCert)
+	addString("sslkey", p.SSLKey)
+	addBool("sslsni", p.SSLSNI)
+
+	var options []string
+	if p.SearchPath != "" {
+		options = append(options, optionVariable("search_path", p.SearchPath))
+	}
+	if p.StatementTimeout != 0 {
+		options = append(options, optionVariable("statement_timeout", strconv.Itoa(p.StatementTimeout)))
+	}
+	for _, v := range p.Variables {
+		options = append(options, optionVariable(v.Name, v.Value))
+	}
+	addString("options", strings.Join(options, " "))
+
+	uri.RawQuery = parameters.Encode()
+
+	return uri.String(), nil
+}
+
+// optionVariable returns -c name=value for options parameter.
+func optionVariable(name, value string) string {
+	return "-c " + escapeOption(name) + "=" + escapeOption(value)
+}
+
+// escapeOption escapes spaces and backslashes according to libpq rules,
+// so option is passed to server as single argument.
+func escapeOption(option string) string {
+	return strings.NewReplacer(`\`, `\\`, " ", `\ `).Replace(option)
+}
+
+// joinHosts returns comma separated list of endpoints for multi-host DSN,
+// port is added to hosts without it.
+func joinHosts(hosts []string, port int) string {
+	endpoints := make([]string, len(hosts))
+	for i, host := range hosts {
+		_, _, err := net.SplitHostPort(host)
+		if err == nil || port == 0 {
+			endpoints[i] = host
+			continue
+		}
+		endpoints[i] = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	return strings.Join(endpoints, ",")
+}
+
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParsePostgres converts DSN returned by Postgres.DSN back to config.
+func ParsePostgres(dsn string) (Postgres, error) {
+	uri, query, err := parsePostgresURL(dsn)
+	if err != nil {
+		return Postgres{}, err
+	}
+
+	c := Postgres{Database: strings.TrimPrefix(uri.Path, "/")}
+	if uri.User != nil {
+		c.User = uri.User.Username()
+		c.Password, _ = uri.User.Password()
+	}
+	c.Host, c.Port, c.Hosts, err = parseHosts(uri.Host)
+	if err != nil {
+		return Postgres{}, err
+	}
+	c.DirectoryPath = popParameter(query, "host")
+	if len(query) == 0 {
+		return c, nil
+	}
+
+	p := &PostgresParameters{
+		ApplicationName:         popParameter(query, "application_name"),
+		FallbackApplicationName: popParameter(query, "fallback_application_name"),
+		TargetSessionAttrs:      popParameter(query, "target_session_attrs"),
+		SSLRootCert:             popParameter(query, "sslrootcert"),
+		SSLCert:                 popParameter(query, "sslcert"),
+		SSLKey:                  popParameter(query, "sslkey"),
+	}
+	for key, value := range map[string]*int{
+		"connect_timeout":     &p.ConnectTimeout,
+		"keepalives_idle":     &p.KeepalivesIdle,
+		"keepalives_interval": &p.KeepalivesInterval,
+		"keepalives_count":    &p.KeepalivesCount,
+	} {
+		*value, err = popInt(query, key)
+		if err != nil {
+			return Postgres{}, err
+		}
+	}
+	for key, value := range map[string]**bool{
+		"keepalives": &p.Keepalives,
+		"sslsni":     &p.SSLSNI,
+	} {
+		*value, err = popBool(query, key)
+		if err != nil {
+			return Postgres{}, err
+		}
+	}
+	if mode := popParameter(query, "sslmode"); mode != "" {
+		err = p.Mode.UnmarshalText([]byte(mode))
+		if err != nil {
+			return Postgres{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+		}
+	}
+	if options := popParameter(query, "options"); options != "" {
+		err = p.parseOptions(options)
+		if err != nil {
+			return Postgres{}, err
+		}
+	}
+
+	err = unknownParameters(query)
+	if err != nil {
+		return Postgres{}, err
+	}
+	c.Parameters = p
+
+	return c, nil
+}
+
+// parseOptions sets SearchPath, StatementTimeout and Variables from options parameter.
+func (p *PostgresParameters) parseOptions(options string) error {
+	args := splitOptions(options)
+	for i := 0; i < len(args); i++ {
+		if args[i] != "-c" || i+1 == len(args) {
+			return fmt.Errorf("%w: unsupported option %q", ErrInvalidDSN, args[i])
+		}
+		i++
+
+		name, value := splitVariable(args[i])
+		statementTimeout, err := strconv.Atoi(value)
+		switch {
+		case name == "search_path" && p.SearchPath == "" && p.StatementTimeout == 0 && len(p.Variables) == 0:
+			p.SearchPath = value
+		case name == "statement_timeout" && err == nil && p.StatementTimeout == 0 && len(p.Variables) == 0:
+			p.StatementTimeout = statementTimeout
+		default:
+			p.Variables = append(p.Variables, PostgresVariable{Name: name, Value: value})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package connectors
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	connector "github.com/Meat-Hook/framework/repo/sql"
+)
+
+var (
+	_ yaml.Unmarshaler         = (*SQLiteJournalMode)(nil)
+	_ json.Unmarshaler         = (*SQLiteJournalMode)(nil)
+	_ encoding.TextUnmarshaler = (*SQLiteJournalMode)(nil)
+	_ yaml.Unmarshaler         = (*SQLiteSynchronous)(nil)
+	_ json.Unmarshaler         = (*SQLiteSynchronous)(nil)
+	_ encoding.TextUnmarshaler = (*SQLiteSynchronous)(nil)
+	_ yaml.Unmarshaler         = (*SQLiteCache)(nil)
+	_ json.Unmarshaler         = (*SQLiteCache)(nil)
+	_ encoding.TextUnmarshaler = (*SQLiteCache)(nil)
+	_ connector.Connector      = (*SQLite)(nil)
+	_ connector.Validator      = (*SQLite)(nil)
+)
+
+// SQLiteMemory is a SQLite.Path for in-memory database. It's opened with
+// shared cache, otherwise every connection of pool has own empty database.
+const SQLiteMemory = ":memory:"
+
+// SQLiteJournalMode is a type for setting journal_mode pragma.
+type SQLiteJournalMode uint8
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *SQLiteJournalMode) UnmarshalJSON(b []byte) error {
+	str := ""
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return i.UnmarshalText([]byte(str))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (i *SQLiteJournalMode) UnmarshalYAML(b *yaml.Node) error {
+	return i.UnmarshalText([]byte(b.Value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *SQLiteJournalMode) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case SQLiteJournalDelete.String():
+		*i = SQLiteJournalDelete
+	case SQLiteJournalTruncate.String():
+		*i = SQLiteJournalTruncate
+	case SQLiteJournalPersist.String():
+		*i = SQLiteJournalPersist
+	case SQLiteJournalMemory.String():
+		*i = SQLiteJournalMemory
+	case SQLiteJournalWAL.String():
+		*i = SQLiteJournalWAL
+	case SQLiteJournalOff.String():
+		*i = SQLiteJournalOff
+	default:
+		return fmt.Errorf("unknown mode: %s", str)
+	}
+
+	return nil
+}
+
+// Enum.
+const (
+	_                     SQLiteJournalMode = iota
+	SQLiteJournalDelete                     // DELETE
+	SQLiteJournalTruncate                   // TRUNCATE
+	SQLiteJournalPersist                    // PERSIST
+	SQLiteJournalMemory                     // MEMORY
+	SQLiteJournalWAL                        // WAL
+	SQLiteJournalOff                        // OFF
+)
+
+// SQLiteSynchronous is a type for setting synchronous pragma.
+type SQLiteSynchronous uint8
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *SQLiteSynchronous) UnmarshalJSON(b []byte) error {
+	str := ""
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return i.UnmarshalText([]byte(str))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (i *SQLiteSynchronous) UnmarshalYAML(b *yaml.Node) error {
+	return i.UnmarshalText([]byte(b.Value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *SQLiteSynchronous) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case SQLiteSynchronousOff.String():
+		*i = SQLiteSynchronousOff
+	case SQLiteSynchronousNormal.String():
+		*i = SQLiteSynchronousNormal
+	case SQLiteSynchronousFull.String():
+		*i = SQLiteSynchronousFull
+	case SQLiteSynchronousExtra.String():
+		*i = SQLiteSynchronousExtra
+	default:
+		return fmt.Errorf("unknown level: %s", str)
+	}
+
+	return nil
+}
+
+// Enum.
+const (
+	_                       SQLiteSynchronous = iota
+	SQLiteSynchronousOff                      // OFF
+	SQLiteSynchronousNormal                   // NORMAL
+	SQLiteSynchronousFull                     // FULL
+	SQLiteSynchronousExtra                    // EXTRA
+)
+
+// SQLiteCache is a type for setting cache mode.
+type SQLiteCache uint8
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *SQLiteCache) UnmarshalJSON(b []byte) error {
+	str := ""
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return i.UnmarshalText([]byte(str))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (i *SQLiteCache) UnmarshalYAML(b *yaml.Node) error {
+	return i.UnmarshalText([]byte(b.Value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *SQLiteCache) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case SQLiteCacheShared.String():
+		*i = SQLiteCacheShared
+	case SQLiteCachePrivate.String():
+		*i = SQLiteCachePrivate
+	default:
+		return fmt.Errorf("unknown mode: %s", str)
+	}
+
+	return nil
+}
+
+// Enum.
+const (
+	_                  SQLiteCache = iota
+	SQLiteCacheShared              // shared
+	SQLiteCachePrivate             // private
+)
+
+type (
+	// SQLiteParameters contains pragmas and URI parameters for opening database.
+	SQLiteParameters struct {
+		JournalMode SQLiteJournalMode `yaml:"journal_mode" json:"journal_mode" hcl:"journal_mode"`
+		// BusyTimeout in milliseconds.
+		BusyTimeout int               `yaml:"busy_timeout" json:"busy_timeout" hcl:"busy_timeout"`
+		ForeignKeys bool              `yaml:"foreign_keys" json:"foreign_keys" hcl:"foreign_keys"`
+		Synchronous SQLiteSynchronous `yaml:"synchronous" json:"synchronous" hcl:"synchronous"`
+		Cache       SQLiteCache       `yaml:"cache" json:"cache" hcl:"cache"`
+	}
+
+	// SQLite config for opening SQLite database by pure-Go driver modernc.org/sqlite.
+	SQLite struct {
+		// Path is a database file path or SQLiteMemory.
+		Path       string            `yaml:"path" json:"path" hcl:"path"`
+		Parameters *SQLiteParameters `yaml:"parameters" json:"parameters" hcl:"parameters,block"`
+	}
+)
+
+// Validate returns ValidationError with all problems of config which
+// can't be used for connecting.
+func (c SQLite) Validate() error {
+	v := &validator{}
+	v.check(c.Path != "", "path", "is required")
+	if c.Parameters != nil {
+		v.nonNegative("parameters.busy_timeout", c.Parameters.BusyTimeout)
+		v.check(c.Path != SQLiteMemory || c.Parameters.Cache != SQLiteCachePrivate,
+			"parameters.cache", "must be shared for in-memory database")
+	}
+
+	return v.err()
+}
+
+// DSN convert struct to DSN and returns connection string.
+func (c SQLite) DSN() (string, error) {
+	if c.Path == "" {
+		return "", fmt.Errorf("%w: path is required", ErrInvalidConfig)
+	}
+
+	p := c.Parameters
+	if p == nil {
+		p = &SQLiteParameters{}
+	}
+	if c.Path == SQLiteMemory && p.Cache == SQLiteCachePrivate {
+		return "", fmt.Errorf("%w: in-memory database requires shared cache", ErrInvalidConfig)
+	}
+
+	dsn := "file:" + (&url.URL{Path: c.Path}).EscapedPath()
+	parameters := url.Values{}
+	switch {
+	case p.Cache != 0:
+		parameters.Add("cache", p.Cache.String())
+	case c.Path == SQLiteMemory:
+		parameters.Add("cache", SQLiteCacheShared.String())
+	}
+	if p.JournalMode != 0 {
+		parameters.Add("_pragma", "journal_mode("+p.JournalMode.String()+")")
+	}
+	if p.BusyTimeout != 0 {
+		parameters.Add("_pragma", "busy_timeout("+strconv.Itoa(p.BusyTimeout)+")")
+	}
+	if p.ForeignKeys {
+		parameters.Add("_pragma", "foreign_keys(1)")
+	}
+	if p.Synchronous != 0 {
+		parameters.Add("_pragma", "synchronous("+p.Synchronous.String()+")")
+	}
+	if len(parameters) == 0 {
+		return dsn, nil
+	}
+
+	return dsn + "?" + parameters.Encode(), nil
+}
+
+// ParseSQLite converts DSN returned by SQLite.DSN back to config.
+func ParseSQLite(dsn string) (SQLite, error) {
+	if !strings.HasPrefix(dsn, "file:") {
+		return SQLite{}, fmt.Errorf("%w: file: prefix is required", ErrInvalidDSN)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(dsn, "file:"), "?", 2)
+	path, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return SQLite{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+	c := SQLite{Path: path}
+	if len(parts) == 1 {
+		return c, nil
+	}
+
+	query, err := url.ParseQuery(parts[1])
+	if err != nil {
+		return SQLite{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+	}
+
+	p := &SQLiteParameters{}
+	if cache := popParameter(query, "cache"); cache != "" {
+		err = p.Cache.UnmarshalText([]byte(cache))
+		if err != nil {
+			return SQLite{}, fmt.Errorf("%w: %s", ErrInvalidDSN, err)
+		}
+	}
+	for _, pragma := range query["_pragma"] {
+		err = p.parsePragma(pragma)
+		if err != nil {
+			return SQLite{}, err
+		}
+	}
+	query.Del("_pragma")
+
+	err = unknownParameters(query)
+	if err != nil {
+		return SQLite{}, err
+	}
+	if *p != (SQLiteParameters{}) {
+		c.Parameters = p
+	}
+
+	return c, nil
+}
+
+// parsePragma sets parameter from name(value) pragma.
+func (p *SQLiteParameters) parsePragma(pragma string) error {
+	i := strings.IndexByte(pragma, '(')
+	if i == -1 || !strings.HasSuffix(pragma, ")") {
+		return fmt.Errorf("%w: invalid pragma %q", ErrInvalidDSN, pragma)
+	}
+	name, value := pragma[:i], pragma[i+1:len(pragma)-1]
+
+	var err error
+	switch name {
+	case "journal_mode":
+		err = p.JournalMode.UnmarshalText([]byte(value))
+	case "busy_timeout":
+		p.BusyTimeout, err = strconv.Atoi(value)
+	case "foreign_keys":
+		p.ForeignKeys, err = strconv.ParseBool(value)
+	case "synchronous":
+		err = p.Synchronous.UnmarshalText([]byte(value))
+	default:
+		return fmt.Errorf("%w: unsupported pragma %q", ErrInvalidDSN, pragma)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: invalid pragma %q: %s", ErrInvalidDSN, pragma, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package connectors_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	_ "modernc.org/sqlite"
+
+	"github.com/Meat-Hook/framework/repo/sql/connectors"
+)
+
+func sqliteAll() connectors.SQLite {
+	return connectors.SQLite{
+		Path: "path/to/app.db",
+		Parameters: &connectors.SQLiteParameters{
+			JournalMode: connectors.SQLiteJournalWAL,
+			BusyTimeout: 5000,
+			ForeignKeys: true,
+			Synchronous: connectors.SQLiteSynchronousNormal,
+			Cache:       connectors.SQLiteCachePrivate,
+		},
+	}
+}
+
+func TestSQLite_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path    string
+		decoder func([]byte, interface{}) error
+	}{
+		"json": {"testdata/sqlite.json", func(b []byte, i interface{}) error { return json.Unmarshal(b, i) }},
+		"yaml": {"testdata/sqlite.yaml", func(b []byte, i interface{}) error { return yaml.Unmarshal(b, i) }},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			b, err := os.ReadFile(tc.path)
+			r.NoError(err)
+			value := connectors.SQLite{}
+			err = tc.decoder(b, &value)
+			r.NoError(err)
+			r.Equal(sqliteAll(), value)
+		})
+	}
+}
+
+func TestSQLite_DSN(t *testing.T) {
+	t.Parallel()
+
+	type T = connectors.SQLite
+	change := func(fn func(*T)) T {
+		t := sqliteAll()
+		fn(&t)
+		return t
+	}
+
+	testCases := map[string]struct {
+		cfg     T
+		exp     string
+		wantErr error
+	}{
+		"all": {sqliteAll(), "file:path/to/app.db?_pragma=journal_mode%28WAL%29&_pragma=busy_timeout%285000%29&_pragma=foreign_keys%281%29&_pragma=synchronous%28NORMAL%29&cache=private", nil},
+		"memory": {change(func(t *T) {
+			t.Path = connectors.SQLiteMemory
+			t.Parameters = &connectors.SQLiteParameters{Cache: connectors.SQLiteCacheShared}
+		}),
+			"file::memory:?cache=shared", nil},
+		"memory_default":     {change(func(t *T) { t.Path = connectors.SQLiteMemory; t.Parameters = nil }), "file::memory:?cache=shared", nil},
+		"memory_private":     {change(func(t *T) { t.Path = connectors.SQLiteMemory }), "", connectors.ErrInvalidConfig},
+		"escaped_path":       {change(func(t *T) { t.Path = "/tmp/my app?.db"; t.Parameters = nil }), "file:/tmp/my%20app%3F.db", nil},
+		"without_parameters": {change(func(t *T) { t.Parameters = nil }), "file:path/to/app.db", nil},
+		"empty_parameters":   {change(func(t *T) { t.Parameters = &connectors.SQLiteParameters{} }), "file:path/to/app.db", nil},
+		"without_path":       {change(func(t *T) { t.Path = "" }), "", connectors.ErrInvalidConfig},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			dsn, err := tc.cfg.DSN()
+			r.ErrorIs(err, tc.wantErr)
+			r.Equal(tc.exp, dsn)
+			if tc.wantErr != nil {
+				return
+			}
+
+			parsed, err := connectors.ParseSQLite(dsn)
+			r.NoError(err)
+			dsn, err = parsed.DSN()
+			r.NoError(err)
+			r.Equal(tc.exp, dsn)
+		})
+	}
+}
+
+func TestSQLite_Validate(t *testing.T) {
+	t.Parallel()
+
+	r := require.New(t)
+	r.NoError(sqliteAll().Validate())
+	requireFieldErrors(t, connectors.SQLite{Parameters: &connectors.SQLiteParameters{BusyTimeout: -1}}.Validate(),
+		"path", "parameters.busy_timeout")
+	requireFieldErrors(t, connectors.SQLite{Path: connectors.SQLiteMemory, Parameters: &connectors.SQLiteParameters{Cache: connectors.SQLiteCachePrivate}}.Validate(),
+		"parameters.cache")
+	r.NoError(connectors.SQLite{Path: connectors.SQLiteMemory}.Validate())
+}
+
+func TestSQLite_Open_Memory(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dsn, err := connectors.SQLite{Path: connectors.SQLiteMemory}.DSN()
+	r.NoError(err)
+
+	db, err := sql.Open("sqlite", dsn)
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(db.Close()) })
+
+	ctx := context.Background()
+	conn1, err := db.Conn(ctx)
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(conn1.Close()) })
+	conn2, err := db.Conn(ctx)
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(conn2.Close()) })
+
+	_, err = conn1.ExecContext(ctx, "CREATE TABLE open_memory (id INTEGER)")
+	r.NoError(err)
+	_, err = conn2.ExecContext(ctx, "INSERT INTO open_memory (id) VALUES (1)")
+	r.NoError(err)
+}
+
+func TestSQLite_Open(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cfg := sqliteAll()
+	cfg.Path = filepath.Join(t.TempDir(), "app.db")
+	dsn, err := cfg.DSN()
+	r.NoError(err)
+
+	db, err := sql.Open("sqlite", dsn)
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(db.Close()) })
+
+	var (
+		journalMode string
+		busyTimeout int
+		foreignKeys bool
+		synchronous int
+	)
+	r.NoError(db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	r.NoError(db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout))
+	r.NoError(db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys))
+	r.NoError(db.QueryRow("PRAGMA synchronous").Scan(&synchronous))
+	r.Equal("wal", journalMode)
+	r.Equal(5000, busyTimeout)
+	r.True(foreignKeys)
+	r.Equal(1, synchronous) // NORMAL.
+	r.FileExists(cfg.Path)
+}
+
+func TestParseSQLite(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		dsn     string
+		want    connectors.SQLite
+		wantErr error
+	}{
+		"all": {"file:path/to/app.db?_pragma=journal_mode%28WAL%29&_pragma=busy_timeout%285000%29&_pragma=foreign_keys%281%29&_pragma=synchronous%28NORMAL%29&cache=private",
+			sqliteAll(), nil},
+		"escaped_path":   {"file:/tmp/my%20app%3F.db", connectors.SQLite{Path: "/tmp/my app?.db"}, nil},
+		"without_prefix": {"path/to/app.db", connectors.SQLite{}, connectors.ErrInvalidDSN},
+		"unknown_pragma": {"file:app.db?_pragma=cache_size%28100%29", connectors.SQLite{}, connectors.ErrInvalidDSN},
+		"invalid_pragma": {"file:app.db?_pragma=busy_timeout%28x%29", connectors.SQLite{}, connectors.ErrInvalidDSN},
+		"unknown_param":  {"file:app.db?mode=ro", connectors.SQLite{}, connectors.ErrInvalidDSN},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			c, err := connectors.ParseSQLite(tc.dsn)
+			r.ErrorIs(err, tc.wantErr)
+			r.Equal(tc.want, c)
+		})
+	}
+}
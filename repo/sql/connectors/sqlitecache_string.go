@@ -0,0 +1,25 @@
+// Code generated by "stringer -type=SQLiteCache -linecomment"; DO NOT EDIT.
+
+package connectors
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SQLiteCacheShared-1]
+	_ = x[SQLiteCachePrivate-2]
+}
+
+const _SQLiteCache_name = "sharedprivate"
+
+var _SQLiteCache_index = [...]uint8{0, 6, 13}
+
+func (i SQLiteCache) String() string {
+	i -= 1
+	if i >= SQLiteCache(len(_SQLiteCache_index)-1) {
+		return "SQLiteCache(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _SQLiteCache_name[_SQLiteCache_index[i]:_SQLiteCache_index[i+1]]
+}
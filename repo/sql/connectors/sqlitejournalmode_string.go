@@ -0,0 +1,29 @@
+// Code generated by "stringer -type=SQLiteJournalMode -linecomment"; DO NOT EDIT.
+
+package connectors
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SQLiteJournalDelete-1]
+	_ = x[SQLiteJournalTruncate-2]
+	_ = x[SQLiteJournalPersist-3]
+	_ = x[SQLiteJournalMemory-4]
+	_ = x[SQLiteJournalWAL-5]
+	_ = x[SQLiteJournalOff-6]
+}
+
+const _SQLiteJournalMode_name = "DELETETRUNCATEPERSISTMEMORYWALOFF"
+
+var _SQLiteJournalMode_index = [...]uint8{0, 6, 14, 21, 27, 30, 33}
+
+func (i SQLiteJournalMode) String() string {
+	i -= 1
+	if i >= SQLiteJournalMode(len(_SQLiteJournalMode_index)-1) {
+		return "SQLiteJournalMode(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _SQLiteJournalMode_name[_SQLiteJournalMode_index[i]:_SQLiteJournalMode_index[i+1]]
+}
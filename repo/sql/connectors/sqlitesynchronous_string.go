@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=SQLiteSynchronous -linecomment"; DO NOT EDIT.
+
+package connectors
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SQLiteSynchronousOff-1]
+	_ = x[SQLiteSynchronousNormal-2]
+	_ = x[SQLiteSynchronousFull-3]
+	_ = x[SQLiteSynchronousExtra-4]
+}
+
+const _SQLiteSynchronous_name = "OFFNORMALFULLEXTRA"
+
+var _SQLiteSynchronous_index = [...]uint8{0, 3, 9, 13, 18}
+
+func (i SQLiteSynchronous) String() string {
+	i -= 1
+	if i >= SQLiteSynchronous(len(_SQLiteSynchronous_index)-1) {
+		return "SQLiteSynchronous(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _SQLiteSynchronous_name[_SQLiteSynchronous_index[i]:_SQLiteSynchronous_index[i+1]]
+}
@@ -0,0 +1,97 @@
+package connectors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrInvalidConfig is returned by Validate for config which can't be used for connecting.
+var ErrInvalidConfig = errors.New("invalid config")
+
+var _ error = ValidationError(nil)
+
+// FieldError describes problem with config field.
+type FieldError struct {
+	// Path is a YAML path of field, e.g. parameters.ssl_root_cert.
+	Path    string
+	Problem string
+}
+
+// Error implements error.
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Problem
+}
+
+// ValidationError contains all problems found by Validate, it matches ErrInvalidConfig.
+type ValidationError []FieldError
+
+// Error implements error.
+func (e ValidationError) Error() string {
+	problems := make([]string, len(e))
+	for i := range e {
+		problems[i] = e[i].Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+}
+
+// Is implements errors.Is.
+func (e ValidationError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// validator collects problems of config.
+type validator struct {
+	errs ValidationError
+}
+
+// check adds problem for path if ok is false.
+func (v *validator) check(ok bool, path, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Path: path, Problem: fmt.Sprintf(format, args...)})
+	}
+}
+
+// port checks port is in range, 0 means default port.
+func (v *validator) port(path string, port int) {
+	v.check(port >= 0 && port <= 65535, path, "%d is out of range", port)
+}
+
+// nonNegative checks values which are disabled by 0.
+func (v *validator) nonNegative(path string, value int) {
+	v.check(value >= 0, path, "must be non-negative")
+}
+
+// hosts checks one of host, hosts or directory_path is set.
+func (v *validator) hosts(host string, hosts []string, directoryPath string) {
+	v.check(host != "" || len(hosts) != 0 || directoryPath != "", "host", "is required if hosts and directory_path aren't set")
+	v.check(host == "" || len(hosts) == 0, "hosts", "can't be set together with host")
+	v.check(directoryPath == "" || (host == "" && len(hosts) == 0), "directory_path", "can't be set together with host")
+	for i := range hosts {
+		v.check(hosts[i] != "", fmt.Sprintf("hosts[%d]", i), "is required")
+	}
+}
+
+// file checks file exists and is readable if name is set.
+func (v *validator) file(path, name string) {
+	if name == "" {
+		return
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		v.check(false, path, "can't read: %s", err)
+		return
+	}
+	_ = f.Close()
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs
+}
@@ -0,0 +1,40 @@
+package connectors_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql/connectors"
+)
+
+// requireFieldErrors checks err contains problems of fields with given paths only.
+func requireFieldErrors(t *testing.T, err error, paths ...string) {
+	t.Helper()
+	r := require.New(t)
+
+	if len(paths) == 0 {
+		r.NoError(err)
+		return
+	}
+
+	r.ErrorIs(err, connectors.ErrInvalidConfig)
+	var validationErr connectors.ValidationError
+	r.True(errors.As(err, &validationErr))
+	got := make([]string, len(validationErr))
+	for i := range validationErr {
+		got[i] = validationErr[i].Path
+	}
+	r.Equal(paths, got)
+}
+
+func TestValidationError_Error(t *testing.T) {
+	t.Parallel()
+
+	err := connectors.ValidationError{
+		{Path: "host", Problem: "is required"},
+		{Path: "port", Problem: "70000 is out of range"},
+	}
+	require.EqualError(t, err, "invalid config: host: is required; port: 70000 is out of range")
+}
@@ -0,0 +1,221 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lib/pq"
+
+	dsnutil "github.com/Meat-Hook/framework/repo/sql/internal/dsn"
+)
+
+// Errors.
+var (
+	ErrUnknownTargetSessionAttrs = errors.New("unknown target_session_attrs")
+	ErrNoSuitableHost            = errors.New("no suitable host")
+)
+
+// Values of target_session_attrs.
+const (
+	targetAny           = "any"
+	targetReadWrite     = "read-write"
+	targetReadOnly      = "read-only"
+	targetPrimary       = "primary"
+	targetStandby       = "standby"
+	targetPreferStandby = "prefer-standby"
+)
+
+var _ driver.Connector = (*failoverConnector)(nil)
+
+// failoverConnector implements multi-host DSN and target_session_attrs
+// for drivers which don't support them (lib/pq): hosts are tried in turn
+// starting from the host of the last successful connection, so pool
+// reconnects to a surviving host after one goes away.
+type failoverConnector struct {
+	driver     driver.Driver
+	dsns       []string           // One DSN for every host.
+	connectors []driver.Connector // One connector for every DSN.
+	attrs      string
+	last       uint32
+}
+
+// newFailoverConnector returns nil if dsn has single host and no target_session_attrs.
+func newFailoverConnector(d driver.Driver, dsn string) (*failoverConnector, error) {
+	dsn, uriHosts := dsnutil.CutHosts(dsn)
+	uri, err := url.Parse(dsn)
+	if err != nil || (uri.Scheme != "postgres" && uri.Scheme != "postgresql") {
+		return nil, nil //nolint:nilerr // Not URL DSN, so it has single host.
+	}
+
+	query := uri.Query()
+	hosts := strings.Split(uriHosts, ",")
+	attrs := query.Get("target_session_attrs")
+	if len(hosts) == 1 && attrs == "" {
+		return nil, nil
+	}
+
+	switch attrs {
+	case "", targetAny, targetReadWrite, targetReadOnly, targetPrimary, targetStandby, targetPreferStandby:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTargetSessionAttrs, attrs)
+	}
+
+	query.Del("target_session_attrs")
+	c := &failoverConnector{driver: d, attrs: attrs}
+	for _, host := range hosts {
+		hostURI := *uri
+		hostURI.Host = host
+		hostURI.RawQuery = query.Encode()
+		connector, err := newConnector(d, hostURI.String())
+		if err != nil {
+			return nil, fmt.Errorf("newConnector: %w", err)
+		}
+		c.dsns = append(c.dsns, hostURI.String())
+		c.connectors = append(c.connectors, connector)
+	}
+
+	return c, nil
+}
+
+// Connect implements driver.Connector.
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.attrs == targetPreferStandby {
+		conn, err := c.connect(ctx, targetStandby)
+		if err == nil {
+			return conn, nil
+		}
+		return c.connect(ctx, targetAny)
+	}
+
+	return c.connect(ctx, c.attrs)
+}
+
+func (c *failoverConnector) connect(ctx context.Context, attrs string) (driver.Conn, error) {
+	start := atomic.LoadUint32(&c.last)
+	var lastErr error
+	for i := range c.connectors {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		n := (int(start) + i) % len(c.connectors)
+		conn, err := c.connectors[n].Connect(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		ok, err := matchSessionAttrs(ctx, conn, attrs)
+		if err != nil {
+			lastErr = err
+		}
+		if !ok {
+			_ = conn.Close()
+			continue
+		}
+
+		atomic.StoreUint32(&c.last, uint32(n))
+		return conn, nil
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuitableHost, attrs)
+	}
+	return nil, lastErr
+}
+
+// Driver implements driver.Connector.
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func matchSessionAttrs(ctx context.Context, conn driver.Conn, attrs string) (bool, error) {
+	switch attrs {
+	case "", targetAny:
+		return true, nil
+	case targetReadWrite, targetReadOnly:
+		readOnly, err := queryValue(ctx, conn, "SHOW transaction_read_only")
+		if err != nil {
+			return false, err
+		}
+		return (readOnly == "on") == (attrs == targetReadOnly), nil
+	default: // Primary or standby.
+		inRecovery, err := queryValue(ctx, conn, "SELECT pg_is_in_recovery()")
+		if err != nil {
+			return false, err
+		}
+		return (inRecovery == "true") == (attrs == targetStandby), nil
+	}
+}
+
+// queryValue returns the first column of the first row formatted as string.
+func queryValue(ctx context.Context, conn driver.Conn, query string) (string, error) {
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return "", fmt.Errorf("%w: driver.QueryerContext isn't implemented", ErrNoSuitableHost)
+	}
+
+	rows, err := queryer.QueryContext(ctx, query, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, len(rows.Columns()))
+	err = rows.Next(dest)
+	if errors.Is(err, io.EOF) || len(dest) == 0 {
+		return "", fmt.Errorf("%w: no result of %s", ErrNoSuitableHost, query)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if b, ok := dest[0].([]byte); ok {
+		return string(b), nil
+	}
+	return fmt.Sprint(dest[0]), nil
+}
+
+// openConnector returns connector which adds failover and keepalives for
+// lib/pq (see failoverConnector and keepaliveConnector) and query tags,
+// or nil if none is needed.
+func openConnector(d driver.Driver, dsn string, tags *QueryTags) (driver.Connector, error) {
+	var connector driver.Connector
+	if _, ok := d.(*pq.Driver); ok {
+		failover, err := newFailoverConnector(d, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("newFailoverConnector: %w", err)
+		}
+		if failover != nil {
+			connector = failover
+		} else {
+			keepalive, err := newKeepaliveConnector(dsn)
+			if err != nil {
+				return nil, fmt.Errorf("newKeepaliveConnector: %w", err)
+			}
+			if keepalive != nil {
+				connector = keepalive
+			}
+		}
+	}
+
+	if tags == nil {
+		return connector, nil
+	}
+
+	if connector == nil {
+		var err error
+		connector, err = newConnector(d, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("newConnector: %w", err)
+		}
+	}
+
+	return &commentConnector{Connector: connector, tags: tags}, nil
+}
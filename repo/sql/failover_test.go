@@ -0,0 +1,226 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var errHostDown = errors.New("host is down")
+
+type fakeHost struct {
+	down    bool
+	standby bool
+}
+
+// fakeDriver opens connections to hosts of DSN according to their state.
+type fakeDriver map[string]*fakeHost
+
+func (d fakeDriver) Open(dsn string) (driver.Conn, error) {
+	uri, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	host := d[uri.Host]
+	if host == nil || host.down {
+		return nil, errHostDown
+	}
+
+	return &fakeConn{host: uri.Host, standby: host.standby}, nil
+}
+
+// OpenConnector implements driver.DriverContext.
+func (d fakeDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	return fakeConnector{driver: d, dsn: dsn}, nil
+}
+
+type fakeConnector struct {
+	driver fakeDriver
+	dsn    string
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return c.driver.Open(c.dsn)
+}
+
+func (c fakeConnector) Driver() driver.Driver { return c.driver }
+
+type fakeConn struct {
+	driver.Conn
+	host    string
+	standby bool
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch query {
+	case "SHOW transaction_read_only":
+		value := "off"
+		if c.standby {
+			value = "on"
+		}
+		return &fakeRows{value: []byte(value)}, nil
+	default:
+		return &fakeRows{value: c.standby}, nil
+	}
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+type fakeRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func TestFailoverConnector(t *testing.T) {
+	t.Parallel()
+
+	const dsn = "postgres://user@primary:5432,standby:5432/db?sslmode=disable&target_session_attrs="
+
+	testCases := map[string]struct {
+		attrs   string
+		primary fakeHost
+		standby fakeHost
+		want    string
+		wantErr error
+	}{
+		"any":                    {"any", fakeHost{down: true}, fakeHost{standby: true}, "standby:5432", nil},
+		"read_write":             {"read-write", fakeHost{}, fakeHost{standby: true}, "primary:5432", nil},
+		"read_write_down":        {"read-write", fakeHost{down: true}, fakeHost{standby: true}, "", errHostDown},
+		"read_write_no_primary":  {"read-write", fakeHost{standby: true}, fakeHost{standby: true}, "", ErrNoSuitableHost},
+		"read_only":              {"read-only", fakeHost{}, fakeHost{standby: true}, "standby:5432", nil},
+		"primary":                {"primary", fakeHost{}, fakeHost{standby: true}, "primary:5432", nil},
+		"standby":                {"standby", fakeHost{}, fakeHost{standby: true}, "standby:5432", nil},
+		"prefer_standby":         {"prefer-standby", fakeHost{}, fakeHost{standby: true}, "standby:5432", nil},
+		"prefer_standby_primary": {"prefer-standby", fakeHost{}, fakeHost{down: true}, "primary:5432", nil},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			d := fakeDriver{"primary:5432": &tc.primary, "standby:5432": &tc.standby}
+			c, err := newFailoverConnector(d, dsn+tc.attrs)
+			r.NoError(err)
+			r.Len(c.dsns, 2)
+			r.Equal("postgres://user@primary:5432/db?sslmode=disable", c.dsns[0])
+
+			conn, err := c.Connect(context.Background())
+			r.ErrorIs(err, tc.wantErr)
+			if tc.wantErr == nil {
+				r.Equal(tc.want, conn.(*fakeConn).host)
+			}
+		})
+	}
+}
+
+func TestFailoverConnector_Reconnect(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	d := fakeDriver{"a:5432": &fakeHost{}, "b:5432": &fakeHost{}}
+	c, err := newFailoverConnector(d, "postgres://a:5432,b:5432/db")
+	r.NoError(err)
+
+	conn, err := c.Connect(context.Background())
+	r.NoError(err)
+	r.Equal("a:5432", conn.(*fakeConn).host)
+
+	d["a:5432"].down = true
+	conn, err = c.Connect(context.Background())
+	r.NoError(err)
+	r.Equal("b:5432", conn.(*fakeConn).host)
+
+	// Last good host is tried first.
+	d["a:5432"].down = false
+	conn, err = c.Connect(context.Background())
+	r.NoError(err)
+	r.Equal("b:5432", conn.(*fakeConn).host)
+}
+
+func TestNewFailoverConnector(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		dsn     string
+		want    bool
+		wantErr error
+	}{
+		"single_host":          {"postgres://a:5432/db", false, nil},
+		"key_value":            {"host=a port=5432", false, nil},
+		"hosts":                {"postgres://a:5432,b:5432/db", true, nil},
+		"ipv6_hosts":           {"postgres://[::1]:5432,[::2]:5432/db", true, nil},
+		"target_session_attrs": {"postgres://a:5432/db?target_session_attrs=read-write", true, nil},
+		"unknown_attrs":        {"postgres://a:5432/db?target_session_attrs=master", false, ErrUnknownTargetSessionAttrs},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			c, err := newFailoverConnector(fakeDriver{}, tc.dsn)
+			r.ErrorIs(err, tc.wantErr)
+			r.Equal(tc.want, c != nil)
+		})
+	}
+}
+
+func TestNewFailoverConnector_IPv6(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, err := newFailoverConnector(fakeDriver{}, "postgres://user:p%40ss@[::1]:5432,[::2]:5433/db?target_session_attrs=any")
+	r.NoError(err)
+	r.Equal([]string{"postgres://user:p%40ss@[::1]:5432/db", "postgres://user:p%40ss@[::2]:5433/db"}, c.dsns)
+}
+
+func TestFailoverConnector_Context(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	d := fakeDriver{"a:5432": &fakeHost{}, "b:5432": &fakeHost{}}
+	c, err := newFailoverConnector(d, "postgres://a:5432,b:5432/db")
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Connect(ctx)
+	r.ErrorIs(err, context.Canceled)
+}
+
+func TestNewFailoverConnector_PQ(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, err := newFailoverConnector(&pq.Driver{}, "postgres://a:5432,b:5432/db")
+	r.NoError(err)
+	r.Len(c.connectors, 2)
+	r.IsType(&pq.Connector{}, c.connectors[0])
+}
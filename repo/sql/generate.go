@@ -0,0 +1,3 @@
+package sql
+
+//go:generate stringer -type=CockroachPriority -linecomment
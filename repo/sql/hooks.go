@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Meat-Hook/framework/repo"
+)
+
+// txHooks contains hooks registered for transaction.
+type txHooks struct {
+	db         *DB
+	methodName string
+
+	mu         sync.Mutex
+	onCommit   []func()
+	onRollback []func(error)
+}
+
+// Hooks of transactions managed by DB.Tx.
+var hooksByTx sync.Map // *sqlx.Tx -> *txHooks.
+
+// OnCommit registers f to be called after transaction is committed.
+// It must be called from DB.Tx callback. Hooks are called in
+// registration order, panics in hooks are recovered and logged.
+// Hooks registered inside Savepoint which is rolled back are discarded.
+func OnCommit(tx *sqlx.Tx, f func()) {
+	hooks := hooksOf(tx)
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.onCommit = append(hooks.onCommit, f)
+}
+
+// OnRollback registers f to be called after transaction is rolled back
+// with the error which caused rollback. It must be called from DB.Tx callback.
+// Hooks are called in registration order, panics in hooks are recovered and logged.
+// Hooks registered inside Savepoint are called after rollback to savepoint.
+func OnRollback(tx *sqlx.Tx, f func(error)) {
+	hooks := hooksOf(tx)
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.onRollback = append(hooks.onRollback, f)
+}
+
+func hooksOf(tx *sqlx.Tx) *txHooks {
+	hooks, ok := hooksByTx.Load(tx)
+	if !ok {
+		panic("transaction isn't managed by DB.Tx")
+	}
+	return hooks.(*txHooks)
+}
+
+func (db *DB) registerHooks(tx *sqlx.Tx, methodName string) *txHooks {
+	hooks := &txHooks{db: db, methodName: methodName}
+	hooksByTx.Store(tx, hooks)
+	return hooks
+}
+
+func unregisterHooks(tx *sqlx.Tx) {
+	hooksByTx.Delete(tx)
+}
+
+// mark returns amount of registered hooks for restoring them by reset.
+func (h *txHooks) mark() (onCommit, onRollback int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.onCommit), len(h.onRollback)
+}
+
+// reset discards hooks registered after mark and returns discarded OnRollback hooks.
+func (h *txHooks) reset(onCommit, onRollback int) []func(error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	discarded := h.onRollback[onRollback:]
+	h.onCommit = h.onCommit[:onCommit]
+	h.onRollback = h.onRollback[:onRollback:onRollback]
+	return discarded
+}
+
+func (h *txHooks) runOnCommit() {
+	for _, f := range h.onCommit {
+		f := f
+		h.run(func() { f() })
+	}
+}
+
+func (h *txHooks) runOnRollback(hooks []func(error), err error) {
+	for _, f := range hooks {
+		f := f
+		h.run(func() { f(err) })
+	}
+}
+
+// run calls hook and reports its panic as programming bug without panicking.
+func (h *txHooks) run(f func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			if collector, ok := h.db.metrics.(repo.BugCollector); ok {
+				collector.Bug(h.methodName)
+			}
+			h.db.logger.Printf("%s: transaction hook panic: %v\n%s", h.methodName, err, debug.Stack())
+		}
+	}()
+	f()
+}
+
+// rollbackErr returns error which caused rollback for OnRollback hooks.
+func rollbackErr(recovered interface{}) error {
+	if err, ok := recovered.(error); ok {
+		return err
+	}
+	return fmt.Errorf("panic: %v", recovered)
+}
@@ -0,0 +1,42 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo"
+)
+
+func TestTxHooks(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	db := &DB{metrics: repo.NoMetric{}, logger: log.New(&buf, "", 0)}
+	tx := &sqlx.Tx{}
+	hooks := db.registerHooks(tx, "Method")
+	defer unregisterHooks(tx)
+
+	var calls []string
+	OnCommit(tx, func() { calls = append(calls, "commit1") })
+	OnRollback(tx, func(err error) { calls = append(calls, "rollback1: "+err.Error()) })
+
+	onCommit, onRollback := hooks.mark()
+	OnCommit(tx, func() { calls = append(calls, "commit2") })
+	OnRollback(tx, func(err error) { calls = append(calls, "rollback2: "+err.Error()) })
+	hooks.runOnRollback(hooks.reset(onCommit, onRollback), errors.New("savepoint"))
+
+	OnCommit(tx, func() { panic("hook") })
+	OnCommit(tx, func() { calls = append(calls, "commit3") })
+	hooks.runOnCommit()
+
+	r.Equal([]string{"rollback2: savepoint", "commit1", "commit3"}, calls)
+	r.Contains(buf.String(), "Method: transaction hook panic: hook")
+	r.Panics(func() { OnCommit(&sqlx.Tx{}, func() {}) })
+}
@@ -0,0 +1,25 @@
+// Package dsn contains helpers for DSN of PostgreSQL compatible databases.
+package dsn
+
+import "strings"
+
+// CutHosts removes hosts from URL DSN and returns them, because url.Parse
+// doesn't support multiple hosts with IPv6 addresses. DSN which isn't URL
+// is returned as is.
+func CutHosts(dsn string) (rest, hosts string) {
+	i := strings.Index(dsn, "://")
+	if i == -1 {
+		return dsn, ""
+	}
+	start := i + len("://")
+
+	end := strings.IndexAny(dsn[start:], "/?#")
+	if end == -1 {
+		end = len(dsn)
+	} else {
+		end += start
+	}
+	start += strings.LastIndex(dsn[start:end], "@") + 1
+
+	return dsn[:start] + dsn[end:], dsn[start:end]
+}
@@ -0,0 +1,38 @@
+package dsn_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql/internal/dsn"
+)
+
+func TestCutHosts(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		dsn       string
+		wantRest  string
+		wantHosts string
+	}{
+		"key_value":  {"host=a port=5432", "host=a port=5432", ""},
+		"host":       {"postgres://a:5432/db", "postgres:///db", "a:5432"},
+		"user":       {"postgres://u:p%40ss@a:5432/db", "postgres://u:p%40ss@/db", "a:5432"},
+		"ipv6_hosts": {"postgres://u@[::1]:5432,[::2]:5433/db?sslmode=disable", "postgres://u@/db?sslmode=disable", "[::1]:5432,[::2]:5433"},
+		"no_path":    {"postgres://a:5432,b:5432", "postgres://", "a:5432,b:5432"},
+		"query":      {"postgres://a?sslmode=disable", "postgres://?sslmode=disable", "a"},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			rest, hosts := dsn.CutHosts(tc.dsn)
+			r.Equal(tc.wantRest, rest)
+			r.Equal(tc.wantHosts, hosts)
+		})
+	}
+}
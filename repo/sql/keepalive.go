@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	dsnutil "github.com/Meat-Hook/framework/repo/sql/internal/dsn"
+)
+
+// Errors.
+var (
+	ErrInvalidKeepalives = errors.New("invalid keepalives")
+)
+
+// Parameters of libpq which lib/pq doesn't support, they would be sent to
+// server as run-time parameters.
+var dialerParameters = []string{"keepalives", "keepalives_idle", "keepalives_interval", "keepalives_count", "sslsni"}
+
+var _ driver.Connector = (*keepaliveConnector)(nil)
+
+// keepaliveConnector implements keepalives parameters of DSN for lib/pq
+// by net.Dialer.KeepAlive. Dialer sets the same idle time and interval,
+// so keepalives_idle is used if both are set, keepalives_count isn't
+// supported and OS default is used. sslsni is removed, lib/pq sends SNI
+// only in verify-full mode.
+type keepaliveConnector struct {
+	dsn    string
+	dialer net.Dialer
+}
+
+// newKeepaliveConnector returns nil if dsn has no keepalives or sslsni.
+func newKeepaliveConnector(dsn string) (*keepaliveConnector, error) {
+	rest, hosts := dsnutil.CutHosts(dsn)
+	uri, err := url.Parse(rest)
+	if err != nil || (uri.Scheme != "postgres" && uri.Scheme != "postgresql") {
+		return nil, nil //nolint:nilerr // Not URL DSN, lib/pq will parse it.
+	}
+
+	query := uri.Query()
+	found := false
+	for _, key := range dialerParameters {
+		found = found || query.Has(key)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	keepalive := time.Duration(0)
+	for _, key := range []string{"keepalives_interval", "keepalives_idle"} {
+		if value := query.Get(key); value != "" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return nil, fmt.Errorf("%w: %s %q", ErrInvalidKeepalives, key, value)
+			}
+			if seconds > 0 {
+				keepalive = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if value := query.Get("keepalives"); value != "" {
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: keepalives %q", ErrInvalidKeepalives, value)
+		}
+		if !on {
+			keepalive = -1
+		}
+	}
+
+	for _, key := range dialerParameters {
+		query.Del(key)
+	}
+	uri.Host = hosts
+	uri.RawQuery = query.Encode()
+	c := &keepaliveConnector{dsn: uri.String(), dialer: net.Dialer{KeepAlive: keepalive}}
+
+	// Validate the rest of DSN now, like pq.NewConnector.
+	_, err = pq.NewConnector(c.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pq.NewConnector: %w", err)
+	}
+
+	return c, nil
+}
+
+// Connect implements driver.Connector.
+func (c *keepaliveConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return pq.DialOpen(ctxDialer{ctx: ctx, dialer: &c.dialer}, c.dsn)
+}
+
+// Driver implements driver.Connector.
+func (c *keepaliveConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+var _ pq.DialerContext = ctxDialer{}
+
+// ctxDialer dials with ctx of Connect, because pq.DialOpen doesn't accept it.
+type ctxDialer struct {
+	ctx    context.Context //nolint:containedctx // Ctx of single Connect call.
+	dialer *net.Dialer
+}
+
+// Dial implements pq.Dialer.
+func (d ctxDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialTimeout implements pq.Dialer.
+func (d ctxDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return d.DialContext(ctx, network, address)
+}
+
+// DialContext implements pq.DialerContext, deadline of ctx
+// (connect_timeout) is applied to ctx of Connect.
+func (d ctxDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialCtx := d.ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithDeadline(dialCtx, deadline)
+		defer cancel()
+	}
+
+	return d.dialer.DialContext(dialCtx, network, address)
+}
@@ -0,0 +1,113 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeepaliveConnector(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		dsn           string
+		want          bool
+		wantDSN       string
+		wantKeepAlive time.Duration
+		wantErr       error
+	}{
+		"without":   {"postgres://a:5432/db?sslmode=disable", false, "", 0, nil},
+		"key_value": {"host=a keepalives=1", false, "", 0, nil},
+		"default":   {"postgres://a:5432/db?keepalives=1", true, "postgres://a:5432/db", 0, nil},
+		"off":       {"postgres://a:5432/db?keepalives=0&keepalives_idle=30", true, "postgres://a:5432/db", -1, nil},
+		"idle": {"postgres://user:p%40ss@a:5432/db?keepalives_count=3&keepalives_idle=30&keepalives_interval=5&sslmode=disable",
+			true, "postgres://user:p%40ss@a:5432/db?sslmode=disable", 30 * time.Second, nil},
+		"interval": {"postgres://[::1]:5432/db?keepalives_interval=5&application_name=app",
+			true, "postgres://[::1]:5432/db?application_name=app", 5 * time.Second, nil},
+		"sslsni":   {"postgres://a:5432/db?sslsni=0", true, "postgres://a:5432/db", 0, nil},
+		"invalid":  {"postgres://a:5432/db?keepalives=on", false, "", 0, ErrInvalidKeepalives},
+		"negative": {"postgres://a:5432/db?keepalives_idle=-1", false, "", 0, ErrInvalidKeepalives},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			c, err := newKeepaliveConnector(tc.dsn)
+			r.ErrorIs(err, tc.wantErr)
+			r.Equal(tc.want, c != nil)
+			if c == nil {
+				return
+			}
+			r.Equal(tc.wantDSN, c.dsn)
+			r.Equal(tc.wantKeepAlive, c.dialer.KeepAlive)
+		})
+	}
+}
+
+func TestKeepaliveConnector_Connect(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(ln.Close()) })
+
+	startup := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(startup)
+			return
+		}
+		defer conn.Close()
+
+		var size uint32
+		err = binary.Read(conn, binary.BigEndian, &size)
+		if err != nil || size < 4 {
+			close(startup)
+			return
+		}
+		msg := make([]byte, size-4)
+		_, err = io.ReadFull(conn, msg)
+		if err != nil {
+			close(startup)
+			return
+		}
+		startup <- msg
+	}()
+
+	c, err := openConnector(&pq.Driver{}, "postgres://user@"+ln.Addr().String()+
+		"/db?sslmode=disable&keepalives=1&keepalives_idle=30&sslsni=0", nil)
+	r.NoError(err)
+	r.IsType(&keepaliveConnector{}, c)
+
+	_, err = c.Connect(context.Background())
+	r.Error(err)
+
+	msg := <-startup
+	r.NotNil(msg)
+	r.Contains(string(msg), "user\x00user\x00")
+	for _, key := range dialerParameters {
+		r.False(bytes.Contains(msg, []byte(key+"\x00")), key)
+	}
+
+	failover, err := openConnector(&pq.Driver{}, "postgres://a:5432,b:5432/db?keepalives_idle=30&target_session_attrs=any", nil)
+	r.NoError(err)
+	r.IsType(&keepaliveConnector{}, failover.(*failoverConnector).connectors[0])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Connect(ctx)
+	r.ErrorIs(err, context.Canceled)
+}
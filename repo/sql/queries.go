@@ -0,0 +1,214 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Errors.
+var (
+	ErrNoQueries      = errors.New("no queries")
+	ErrDuplicateQuery = errors.New("duplicate query")
+)
+
+// Query is a named SQL query.
+type Query struct {
+	// Name is usually DAL method name which uses query.
+	Name string
+	SQL  string
+	// Named is set for queries with named parameters (see sqlx.NamedStmt).
+	Named bool
+	// Source is file:line where query was declared or path of loaded file.
+	Source string
+}
+
+// Queries is a registry of named SQL queries, which DAL declares up front,
+// so they can be validated against real database at startup (see DB.Validate).
+type Queries struct {
+	mu      sync.RWMutex
+	queries map[string]Query
+}
+
+// NewQueries build and returns new Queries.
+func NewQueries() *Queries {
+	return &Queries{
+		queries: make(map[string]Query),
+	}
+}
+
+// Add registers query and returns it, so it can be used for declaring variables:
+//
+//	var getUser = queries.Add("GetUser", `SELECT * FROM users WHERE id = $1`)
+//
+// Panics if query with the same name is already registered.
+func (q *Queries) Add(name, query string) string {
+	q.mustAdd(Query{Name: name, SQL: query, Source: callerSource()})
+	return query
+}
+
+// AddNamed is like Add, but for query with named parameters.
+func (q *Queries) AddNamed(name, query string) string {
+	q.mustAdd(Query{Name: name, SQL: query, Named: true, Source: callerSource()})
+	return query
+}
+
+// AddFS registers every *.sql file in the dir of fsys (e.g. embed.FS),
+// file name without extension is used as query name.
+// Files with *.named.sql extension are registered as queries with named parameters.
+// Returns ErrDuplicateQuery if query with the same name is already registered.
+func (q *Queries) AddFS(fsys fs.FS, dir string) error {
+	paths, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("fs.Glob: %w", err)
+	}
+
+	for _, p := range paths {
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("fs.ReadFile: %w", err)
+		}
+
+		name := strings.TrimSuffix(path.Base(p), ".sql")
+		named := strings.HasSuffix(name, ".named")
+		err = q.add(Query{
+			Name:   strings.TrimSuffix(name, ".named"),
+			SQL:    string(b),
+			Named:  named,
+			Source: p,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns query by name. Panics if query isn't registered.
+func (q *Queries) Get(name string) string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	query, ok := q.queries[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown query: %s", name))
+	}
+
+	return query.SQL
+}
+
+// All returns all registered queries sorted by name.
+func (q *Queries) All() []Query {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	queries := make([]Query, 0, len(q.queries))
+	for _, query := range q.queries {
+		queries = append(queries, query)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+
+	return queries
+}
+
+func (q *Queries) add(query Query) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if prev, ok := q.queries[query.Name]; ok {
+		return fmt.Errorf("%w %s: %s and %s", ErrDuplicateQuery, query.Name, prev.Source, query.Source)
+	}
+	q.queries[query.Name] = query
+
+	return nil
+}
+
+func (q *Queries) mustAdd(query Query) {
+	err := q.add(query)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func callerSource() string {
+	_, file, line, _ := runtime.Caller(2)
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// QueryError describes query which is failed validation.
+type QueryError struct {
+	Query Query
+	Err   error
+}
+
+// Error implements error.
+func (e QueryError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Query.Name, e.Query.Source, e.Err)
+}
+
+// Unwrap returns original error.
+func (e QueryError) Unwrap() error {
+	return e.Err
+}
+
+// QueryErrors contains all queries which are failed validation.
+type QueryErrors []QueryError
+
+// Error implements error.
+func (e QueryErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return fmt.Sprintf("%d broken queries:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// Validate prepares every query of Config.Queries against database and returns
+// QueryErrors with all broken queries. It's called by New, but may be
+// called later, e.g. after migrations.
+func (db *DB) Validate(ctx context.Context) error {
+	if db.queries == nil {
+		return ErrNoQueries
+	}
+
+	var errs QueryErrors
+	for _, query := range db.queries.All() {
+		err := db.prepare(ctx, query)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			errs = append(errs, QueryError{Query: query, Err: err})
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (db *DB) prepare(ctx context.Context, query Query) error {
+	if query.Named {
+		stmt, err := db.conn.PrepareNamedContext(ctx, query.SQL)
+		if err != nil {
+			return err
+		}
+		return stmt.Close()
+	}
+
+	stmt, err := db.conn.PreparexContext(ctx, query.SQL)
+	if err != nil {
+		return err
+	}
+	return stmt.Close()
+}
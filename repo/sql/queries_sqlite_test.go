@@ -0,0 +1,42 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"modernc.org/sqlite"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+func TestDB_Validate(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ctx := context.Background()
+	queries := sql.NewQueries()
+	queries.Add("Now", `SELECT now()`)
+	queries.AddNamed("Echo", `SELECT :value`)
+	cfg := sql.Config{Queries: queries}
+
+	db, _ := newRecordingSQLite(t, cfg)
+	r.NoError(db.Validate(ctx))
+
+	queries.Add("ListUsers", `SELECT * FROM users`)
+	var queryErrs sql.QueryErrors
+	r.ErrorAs(db.Validate(ctx), &queryErrs)
+	r.Len(queryErrs, 1)
+	r.Equal("ListUsers", queryErrs[0].Query.Name)
+	r.Contains(queryErrs[0].Query.Source, "queries_sqlite_test.go:")
+	r.ErrorAs(queryErrs[0], new(*sqlite.Error))
+
+	_, err := sql.New(ctx, recordingDriverName, cfg, sqltest.SQLiteMemory{})
+	r.ErrorAs(err, &queryErrs)
+	r.Len(queryErrs, 1)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	r.ErrorIs(db.Validate(canceled), context.Canceled)
+}
@@ -0,0 +1,58 @@
+package sql_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+func TestQueries(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		"queries/GetUser.sql":             {Data: []byte(`SELECT * FROM users WHERE id = $1`)},
+		"queries/CreateUser.named.sql":    {Data: []byte(`INSERT INTO users (name) VALUES (:name)`)},
+		"queries/README.md":               {Data: []byte(`not a query`)},
+		"queries/nested/ListSessions.sql": {Data: []byte(`SELECT * FROM sessions`)},
+	}
+
+	queries := sql.NewQueries()
+	r.Equal(`SELECT 1`, queries.Add("Ping", `SELECT 1`))
+	r.Equal(`UPDATE users SET name = :name`, queries.AddNamed("UpdateUser", `UPDATE users SET name = :name`))
+	r.NoError(queries.AddFS(fsys, "queries"))
+
+	r.Equal(`SELECT * FROM users WHERE id = $1`, queries.Get("GetUser"))
+	r.Panics(func() { queries.Get("ListSessions") })
+	r.Panics(func() { queries.Add("GetUser", `SELECT 1`) })
+	r.ErrorIs(queries.AddFS(fsys, "queries"), sql.ErrDuplicateQuery)
+
+	all := queries.All()
+	r.Len(all, 4)
+	r.Equal(sql.Query{Name: "CreateUser", SQL: `INSERT INTO users (name) VALUES (:name)`, Named: true, Source: "queries/CreateUser.named.sql"}, all[0])
+	r.Equal("GetUser", all[1].Name)
+	r.Equal("Ping", all[2].Name)
+	r.Contains(all[2].Source, "queries_test.go:")
+	r.Equal("UpdateUser", all[3].Name)
+	r.True(all[3].Named)
+}
+
+func TestQueryErrors(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	errSyntax := errors.New("syntax error")
+	err := error(sql.QueryErrors{
+		{Query: sql.Query{Name: "GetUser", Source: "user.go:10"}, Err: errSyntax},
+		{Query: sql.Query{Name: "ListUsers", Source: "queries/ListUsers.sql"}, Err: errSyntax},
+	})
+
+	r.Equal("2 broken queries:\nGetUser (user.go:10): syntax error\nListUsers (queries/ListUsers.sql): syntax error", err.Error())
+	var queryErrs sql.QueryErrors
+	r.True(errors.As(err, &queryErrs))
+	r.ErrorIs(queryErrs[0], errSyntax)
+}
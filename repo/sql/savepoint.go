@@ -0,0 +1,78 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidSavepoint is returned for invalid savepoint name.
+var ErrInvalidSavepoint = errors.New("invalid savepoint name")
+
+var savepointName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+
+// Savepoint provides partial rollback inside transaction, it must be
+// called from DB.Tx callback:
+// - creates savepoint before calling f,
+// - rolls back to savepoint if f returns error or panics,
+// - releases savepoint if f succeeds.
+// Transaction stays usable after rollback to savepoint, so caller may
+// handle returned error and continue. Savepoints may be nested.
+// Rollback to savepoint discards OnCommit hooks registered by f and
+// calls OnRollback hooks registered by f.
+func Savepoint(ctx context.Context, tx *sqlx.Tx, name string, f func(*sqlx.Tx) error) (err error) {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSavepoint, name)
+	}
+	name = pq.QuoteIdentifier(name)
+
+	_, err = tx.ExecContext(ctx, `SAVEPOINT `+name)
+	if err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+
+	// Transaction may be not managed by DB.Tx, so it may have no hooks.
+	var (
+		hooks                *txHooks
+		onCommit, onRollback int
+	)
+	if value, ok := hooksByTx.Load(tx); ok {
+		hooks = value.(*txHooks)
+		onCommit, onRollback = hooks.mark()
+	}
+	rollback := func(cause error) error {
+		_, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT `+name)
+		if hooks != nil {
+			hooks.runOnRollback(hooks.reset(onCommit, onRollback), cause)
+		}
+		return err
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			if errRollback := rollback(rollbackErr(err)); errRollback != nil {
+				err = fmt.Errorf("%v: %s", err, errRollback)
+			}
+			panic(err)
+		}
+	}()
+
+	err = f(tx)
+	if err != nil {
+		if errRollback := rollback(err); errRollback != nil {
+			err = fmt.Errorf("%w: %s", err, errRollback)
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT `+name)
+	if err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+func TestSavepoint_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{"", "1savepoint", "savepoint-1", "s; COMMIT"}
+	for _, name := range tests {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			err := sql.Savepoint(context.Background(), nil, name, func(*sqlx.Tx) error { return nil })
+			r.ErrorIs(err, sql.ErrInvalidSavepoint)
+		})
+	}
+}
+
+var errSavepoint = errors.New("savepoint")
+
+type savepointRepo struct {
+	db *sql.DB
+}
+
+func (r savepointRepo) Migrate(ctx context.Context) error {
+	return r.db.NoTx(func(db *sqlx.DB) error {
+		_, err := db.ExecContext(ctx, `CREATE TABLE items (key TEXT NOT NULL)`)
+		return err
+	})
+}
+
+func (r savepointRepo) Keys(ctx context.Context) (keys []string, err error) {
+	err = r.db.NoTx(func(db *sqlx.DB) error {
+		return db.SelectContext(ctx, &keys, `SELECT key FROM items ORDER BY key`)
+	})
+	return keys, err
+}
+
+// Add adds first key in transaction and other keys in nested savepoints,
+// the last savepoint calls f after adding its key.
+func (r savepointRepo) Add(ctx context.Context, f func() error, keys ...string) (err error) {
+	return r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		var add func(i int) error
+		add = func(i int) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO items (key) VALUES ($1)`, keys[i])
+			switch {
+			case err != nil:
+				return err
+			case i == len(keys)-1:
+				return f()
+			}
+			return sql.Savepoint(ctx, tx, fmt.Sprintf("sp%d", i+1), func(*sqlx.Tx) error { return add(i + 1) })
+		}
+		return add(0)
+	})
+}
+
+// AddIgnoring adds "outer" key in transaction and the key in savepoint
+// which calls f, error or panic of savepoint is ignored. Returns which
+// hooks registered in savepoint were called.
+func (r savepointRepo) AddIgnoring(ctx context.Context, key string, f func() error) (committed, rolledBack bool, err error) {
+	err = r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO items (key) VALUES ('outer')`)
+		if err != nil {
+			return err
+		}
+
+		func() {
+			defer func() { _ = recover() }()
+			_ = sql.Savepoint(ctx, tx, "sp", func(tx *sqlx.Tx) error {
+				sql.OnCommit(tx, func() { committed = true })
+				sql.OnRollback(tx, func(error) { rolledBack = true })
+				_, err := tx.ExecContext(ctx, `INSERT INTO items (key) VALUES ($1)`, key)
+				if err != nil {
+					return err
+				}
+				return f()
+			})
+		}()
+		return nil
+	})
+	return committed, rolledBack, err
+}
+
+func TestSavepoint(t *testing.T) {
+	t.Parallel()
+
+	ok := func() error { return nil }
+	fail := func() error { return errSavepoint }
+	tests := map[string]struct {
+		f              func() error
+		wantCommitted  bool
+		wantRolledBack bool
+		wantKeys       []string
+		wantStatements []string
+	}{
+		"release": {ok, true, false, []string{"inner", "outer"}, []string{`SAVEPOINT "sp"`, `RELEASE SAVEPOINT "sp"`}},
+		"error":   {fail, false, true, []string{"outer"}, []string{`SAVEPOINT "sp"`, `ROLLBACK TO SAVEPOINT "sp"`}},
+		"panic":   {func() error { panic(errSavepoint) }, false, true, []string{"outer"}, []string{`SAVEPOINT "sp"`, `ROLLBACK TO SAVEPOINT "sp"`}},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+
+			db, recording := newRecordingSQLite(t, sql.Config{})
+			repo := savepointRepo{db: db}
+			r.NoError(repo.Migrate(ctx))
+			recording.reset()
+
+			committed, rolledBack, err := repo.AddIgnoring(ctx, "inner", tc.f)
+			r.NoError(err)
+			r.Equal(tc.wantCommitted, committed)
+			r.Equal(tc.wantRolledBack, rolledBack)
+			r.Equal(append([]string{`INSERT INTO items (key) VALUES ('outer')`, tc.wantStatements[0],
+				`INSERT INTO items (key) VALUES ($1)`}, tc.wantStatements[1:]...), recording.all())
+
+			keys, err := repo.Keys(ctx)
+			r.NoError(err)
+			r.Equal(tc.wantKeys, keys)
+		})
+	}
+}
+
+func TestSavepoint_Nested(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	db, recording := newRecordingSQLite(t, sql.Config{ReturnErrs: []error{errSavepoint}})
+	repo := savepointRepo{db: db}
+	r.NoError(repo.Migrate(ctx))
+
+	recording.reset()
+	r.NoError(repo.Add(ctx, func() error { return nil }, "a", "b", "c"))
+	r.Equal([]string{
+		`INSERT INTO items (key) VALUES ($1)`,
+		`SAVEPOINT "sp1"`,
+		`INSERT INTO items (key) VALUES ($1)`,
+		`SAVEPOINT "sp2"`,
+		`INSERT INTO items (key) VALUES ($1)`,
+		`RELEASE SAVEPOINT "sp2"`,
+		`RELEASE SAVEPOINT "sp1"`,
+	}, recording.all())
+
+	err := repo.Add(ctx, func() error { return errSavepoint }, "d", "e", "f")
+	r.ErrorIs(err, errSavepoint)
+
+	keys, err := repo.Keys(ctx)
+	r.NoError(err)
+	r.Equal([]string{"a", "b", "c"}, keys)
+}
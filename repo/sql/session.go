@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Errors.
+var (
+	ErrUnknownSessionVariable = errors.New("unknown session variable")
+	ErrInvalidSessionVariable = errors.New("invalid session variable name")
+)
+
+// Session variable name like `statement_timeout` or `app.tenant_id`.
+var sessionVariableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)*$`)
+
+type (
+	sessionVariablesKey struct{}
+
+	sessionVariable struct {
+		name  string
+		value string
+	}
+)
+
+// WithSessionVariable returns a copy of ctx which carries session variable.
+// DB.Tx sets all carried variables with SET LOCAL semantics at transaction start,
+// so they can be used by row-level security policies via current_setting(name).
+// Variable must be registered in Config.SessionVariables, otherwise DB.Tx
+// returns ErrUnknownSessionVariable.
+func WithSessionVariable(ctx context.Context, name, value string) context.Context {
+	vars := sessionVariables(ctx)
+	newVars := make([]sessionVariable, 0, len(vars)+1)
+	for i := range vars {
+		if vars[i].name != name {
+			newVars = append(newVars, vars[i])
+		}
+	}
+	newVars = append(newVars, sessionVariable{name: name, value: value})
+
+	return context.WithValue(ctx, sessionVariablesKey{}, newVars)
+}
+
+// SessionVariable returns value of session variable carried by ctx.
+func SessionVariable(ctx context.Context, name string) (string, bool) {
+	vars := sessionVariables(ctx)
+	for i := range vars {
+		if vars[i].name == name {
+			return vars[i].value, true
+		}
+	}
+
+	return "", false
+}
+
+func sessionVariables(ctx context.Context) []sessionVariable {
+	vars, _ := ctx.Value(sessionVariablesKey{}).([]sessionVariable)
+	return vars
+}
+
+func newSessionRegistry(names []string) (map[string]struct{}, error) {
+	registry := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if !sessionVariableName.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSessionVariable, name)
+		}
+		registry[name] = struct{}{}
+	}
+
+	return registry, nil
+}
+
+// setSessionVariables sets session variables carried by ctx for current transaction only.
+func (db *DB) setSessionVariables(ctx context.Context, tx *sqlx.Tx) error {
+	vars := sessionVariables(ctx)
+	for i := range vars {
+		if _, ok := db.sessionVars[vars[i].name]; !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownSessionVariable, vars[i].name)
+		}
+	}
+
+	for i := range vars {
+		_, err := tx.ExecContext(ctx, `SELECT set_config($1, $2, true)`, vars[i].name, vars[i].value)
+		if err != nil {
+			return fmt.Errorf("set_config %s: %w", vars[i].name, err)
+		}
+	}
+
+	return nil
+}
+
+// setConnVariable sets session variable for pinned connection and returns
+// func for resetting it before connection is released.
+func setConnVariable(ctx context.Context, conn *sqlx.Conn, name, value string) (reset func() error, err error) {
+	_, err = conn.ExecContext(ctx, `SELECT set_config($1, $2, false)`, name, value)
+	if err != nil {
+		return nil, fmt.Errorf("set %s: %w", name, err)
+	}
+
+	reset = func() error {
+		_, err := conn.ExecContext(context.Background(), `RESET `+name)
+		if err != nil {
+			// Connection mustn't return to the pool with changed session.
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			return fmt.Errorf("reset %s: %w", name, err)
+		}
+
+		return nil
+	}
+
+	return reset, nil
+}
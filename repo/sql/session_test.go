@@ -0,0 +1,52 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSessionVariable(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ctx := WithSessionVariable(context.Background(), "app.tenant_id", "1")
+	ctx = WithSessionVariable(ctx, "app.user_id", "2")
+	ctx = WithSessionVariable(ctx, "app.tenant_id", "3")
+
+	value, ok := SessionVariable(ctx, "app.tenant_id")
+	r.True(ok)
+	r.Equal("3", value)
+	_, ok = SessionVariable(ctx, "app.request_id")
+	r.False(ok)
+	r.Equal([]sessionVariable{{"app.user_id", "2"}, {"app.tenant_id", "3"}}, sessionVariables(ctx))
+}
+
+func TestNewSessionRegistry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		given   string
+		wantErr error
+	}{
+		{"statement_timeout", nil},
+		{"app.tenant_id", nil},
+		{"app.tenant.id", nil},
+		{"", ErrInvalidSessionVariable},
+		{"app.", ErrInvalidSessionVariable},
+		{"1app", ErrInvalidSessionVariable},
+		{"app.tenant_id'; DROP TABLE users; --", ErrInvalidSessionVariable},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.given, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			_, err := newSessionRegistry([]string{tc.given})
+			r.ErrorIs(err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Meat-Hook/framework/reflectx"
+)
+
+// LabelShard is a metric label which contains shard index.
+// Metrics must be created with this label (see repo.NewMetrics).
+const LabelShard = "shard"
+
+// Errors.
+var (
+	ErrNoShards        = errors.New("no shards")
+	ErrNoShardStrategy = errors.New("no shard strategy")
+	ErrInvalidRangeMap = errors.New("invalid range map")
+	ErrInvalidDest     = errors.New("dest must be a pointer to slice")
+	ErrInvalidReplicas = errors.New("invalid replicas")
+)
+
+// ShardStrategy chooses shard for the key.
+type ShardStrategy interface {
+	// Shard returns index of shard in [0, shards) for the key.
+	// Returns ErrNoShards if shards isn't positive.
+	Shard(key string, shards int) (int, error)
+}
+
+// shardsValidator is implemented by strategies which depend on shards amount.
+type shardsValidator interface {
+	validate(shards int) error
+}
+
+var (
+	_ ShardStrategy = HashMod{}
+	_ ShardStrategy = (*ConsistentHash)(nil)
+	_ ShardStrategy = RangeMap{}
+)
+
+// HashMod chooses shard as hash of the key modulo amount of shards.
+type HashMod struct{}
+
+// Shard implements ShardStrategy.
+func (HashMod) Shard(key string, shards int) (int, error) {
+	if shards <= 0 {
+		return 0, ErrNoShards
+	}
+
+	return int(hash(key) % uint32(shards)), nil
+}
+
+// DefaultConsistentHashReplicas is a default amount of virtual nodes per shard.
+const DefaultConsistentHashReplicas = 100
+
+// ConsistentHash chooses shard by consistent hashing, so adding a shard
+// moves only a part of keys to the new shard.
+// Zero value uses DefaultConsistentHashReplicas.
+type ConsistentHash struct {
+	replicas int
+
+	mu    sync.Mutex
+	rings map[int][]ringNode
+}
+
+type ringNode struct {
+	hash  uint32
+	shard int
+}
+
+// NewConsistentHash build and returns new ConsistentHash.
+// Replicas is an amount of virtual nodes per shard, DefaultConsistentHashReplicas is used if it's 0.
+// Returns ErrInvalidReplicas if replicas is negative.
+func NewConsistentHash(replicas int) (*ConsistentHash, error) {
+	if replicas < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidReplicas, replicas)
+	}
+
+	return &ConsistentHash{replicas: replicas}, nil
+}
+
+// Shard implements ShardStrategy.
+func (c *ConsistentHash) Shard(key string, shards int) (int, error) {
+	if shards <= 0 {
+		return 0, ErrNoShards
+	}
+
+	ring := c.ring(shards)
+	h := hash(key)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+
+	return ring[i].shard, nil
+}
+
+func (c *ConsistentHash) ring(shards int) []ringNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[shards]
+	if ok {
+		return ring
+	}
+	if c.rings == nil {
+		c.rings = make(map[int][]ringNode)
+	}
+
+	replicas := c.replicas
+	if replicas <= 0 {
+		replicas = DefaultConsistentHashReplicas
+	}
+
+	ring = make([]ringNode, 0, shards*replicas)
+	for shard := 0; shard < shards; shard++ {
+		for replica := 0; replica < replicas; replica++ {
+			ring = append(ring, ringNode{
+				hash:  hash(strconv.Itoa(shard) + "-" + strconv.Itoa(replica)),
+				shard: shard,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	c.rings[shards] = ring
+
+	return ring
+}
+
+// RangeMap chooses shard by key ranges.
+// Shard i contains keys which are less than Bounds[i] and not less than Bounds[i-1],
+// the last shard contains all keys which are not less than the last bound.
+// Bounds must be sorted and contain exactly shards-1 values.
+type RangeMap struct {
+	Bounds []string
+}
+
+// Shard implements ShardStrategy.
+func (m RangeMap) Shard(key string, shards int) (int, error) {
+	if shards <= 0 {
+		return 0, ErrNoShards
+	}
+
+	i := sort.Search(len(m.Bounds), func(i int) bool { return key < m.Bounds[i] })
+	if i >= shards {
+		i = shards - 1
+	}
+
+	return i, nil
+}
+
+func (m RangeMap) validate(shards int) error {
+	if len(m.Bounds) != shards-1 {
+		return fmt.Errorf("%w: %d bounds for %d shards", ErrInvalidRangeMap, len(m.Bounds), shards)
+	}
+	if !sort.StringsAreSorted(m.Bounds) {
+		return fmt.Errorf("%w: bounds aren't sorted", ErrInvalidRangeMap)
+	}
+
+	return nil
+}
+
+// hash returns FNV-1a hash of the key with murmur3 finalizer,
+// because FNV-1a alone is poorly distributed for short similar keys.
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum32()
+	sum ^= sum >> 16
+	sum *= 0x85ebca6b
+	sum ^= sum >> 13
+	sum *= 0xc2b2ae35
+	sum ^= sum >> 16
+	return sum
+}
+
+// ShardedDB is a wrapper for sharded sql database, every shard is a DB.
+type ShardedDB struct {
+	shards   []*DB
+	strategy ShardStrategy
+}
+
+// NewSharded build and returns new ShardedDB, every connector makes connection to one shard.
+// Metrics, if they support it, are collected with LabelShard.
+func NewSharded(ctx context.Context, driver string, cfg Config, strategy ShardStrategy, connectors ...Connector) (*ShardedDB, error) {
+	if len(connectors) == 0 {
+		return nil, ErrNoShards
+	}
+	if strategy == nil {
+		return nil, ErrNoShardStrategy
+	}
+
+	if v, ok := strategy.(shardsValidator); ok {
+		err := v.validate(len(connectors))
+		if err != nil {
+			return nil, fmt.Errorf("strategy.validate: %w", err)
+		}
+	}
+
+	sharded := &ShardedDB{
+		shards:   make([]*DB, 0, len(connectors)),
+		strategy: strategy,
+	}
+
+	for i := range connectors {
+		db, err := New(ctx, driver, cfg, connectors[i])
+		if err != nil {
+			_ = sharded.Close()
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		sharded.shards = append(sharded.shards, db)
+	}
+
+	return sharded, nil
+}
+
+// Close implements io.Closer.
+func (s *ShardedDB) Close() (err error) {
+	for i := range s.shards {
+		errClose := s.shards[i].Close()
+		if errClose != nil && err == nil {
+			err = fmt.Errorf("shard %d: %w", i, errClose)
+		}
+	}
+
+	return err
+}
+
+// Shards returns amount of shards.
+func (s *ShardedDB) Shards() int {
+	return len(s.shards)
+}
+
+// Shard returns index of shard for the key.
+func (s *ShardedDB) Shard(key string) (int, error) {
+	i, err := s.strategy.Shard(key, len(s.shards))
+	if err != nil {
+		return 0, fmt.Errorf("s.strategy.Shard: %w", err)
+	}
+
+	return i, nil
+}
+
+// NoTx provides the same DAL method wrapper as DB.NoTx on the shard for the key.
+func (s *ShardedDB) NoTx(key string, f func(*sqlx.DB) error) (err error) {
+	i, err := s.Shard(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", reflectx.CallerMethodName(1), err)
+	}
+	return s.shards[i].noTx(context.Background(), reflectx.CallerMethodName(1), shardLabels(i), func(_ context.Context, db *sqlx.DB) error {
+		return f(db)
+	})
+}
+
+// NoTxContext provides the same DAL method wrapper as DB.NoTxContext on the shard for the key.
+func (s *ShardedDB) NoTxContext(ctx context.Context, key string, f func(context.Context, *sqlx.DB) error) (err error) {
+	i, err := s.Shard(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", reflectx.CallerMethodName(1), err)
+	}
+	return s.shards[i].noTx(ctx, reflectx.CallerMethodName(1), shardLabels(i), f)
+}
+
+// Tx provides the same DAL method wrapper as DB.Tx on the shard for the key.
+func (s *ShardedDB) Tx(ctx context.Context, key string, opts *sql.TxOptions, f func(*sqlx.Tx) error) (err error) {
+	i, err := s.Shard(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", reflectx.CallerMethodName(1), err)
+	}
+	return s.shards[i].tx(ctx, reflectx.CallerMethodName(1), shardLabels(i), opts, f)
+}
+
+// FanOut provides the same DAL method wrapper as DB.NoTx on every shard concurrently.
+// f must be safe for concurrent use. Returns first error by shard order.
+func (s *ShardedDB) FanOut(f func(shard int, db *sqlx.DB) error) error {
+	return s.fanOut(context.Background(), reflectx.CallerMethodName(1), func(_ context.Context, shard int, db *sqlx.DB) error {
+		return f(shard, db)
+	})
+}
+
+// Select executes query on every shard concurrently and merges results into dest
+// by shard order. Dest must be a pointer to slice like for sqlx.Select,
+// otherwise ErrInvalidDest is returned.
+func (s *ShardedDB) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: %T", ErrInvalidDest, dest)
+	}
+	slice := value.Elem()
+
+	results := make([]reflect.Value, len(s.shards))
+	err := s.fanOut(ctx, reflectx.CallerMethodName(1), func(ctx context.Context, shard int, db *sqlx.DB) error {
+		result := reflect.New(slice.Type())
+		err := db.SelectContext(ctx, result.Interface(), query, args...)
+		results[shard] = result.Elem()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range results {
+		slice = reflect.AppendSlice(slice, results[i])
+	}
+	value.Elem().Set(slice)
+
+	return nil
+}
+
+func (s *ShardedDB) fanOut(ctx context.Context, methodName string, f func(ctx context.Context, shard int, db *sqlx.DB) error) error {
+	var (
+		wg     sync.WaitGroup
+		errs   = make([]error, len(s.shards))
+		panics = make([]interface{}, len(s.shards))
+	)
+
+	wg.Add(len(s.shards))
+	for i := range s.shards {
+		i := i
+		go func() {
+			defer wg.Done()
+			defer func() { panics[i] = recover() }()
+
+			errs[i] = s.shards[i].noTx(ctx, methodName, shardLabels(i), func(ctx context.Context, db *sqlx.DB) error {
+				return f(ctx, i, db)
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i := range panics {
+		if panics[i] != nil {
+			panic(panics[i])
+		}
+	}
+
+	for i := range errs {
+		if errs[i] != nil {
+			return fmt.Errorf("shard %d: %w", i, errs[i])
+		}
+	}
+
+	return nil
+}
+
+func shardLabels(shard int) map[string]string {
+	return map[string]string{LabelShard: strconv.Itoa(shard)}
+}
@@ -0,0 +1,196 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+type shardRepo struct {
+	db *sql.ShardedDB
+}
+
+func (r shardRepo) Migrate() error {
+	return r.db.FanOut(func(_ int, db *sqlx.DB) error {
+		_, err := db.Exec(`CREATE TABLE items (key TEXT NOT NULL)`)
+		return err
+	})
+}
+
+func (r shardRepo) AddNoTx(key string) error {
+	return r.db.NoTx(key, func(db *sqlx.DB) error {
+		_, err := db.Exec(`INSERT INTO items (key) VALUES ($1)`, key)
+		return err
+	})
+}
+
+func (r shardRepo) AddTx(ctx context.Context, key string) error {
+	return r.db.Tx(ctx, key, nil, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO items (key) VALUES ($1)`, key)
+		return err
+	})
+}
+
+func (r shardRepo) KeysByShard() ([][]string, error) {
+	keys := make([][]string, r.db.Shards())
+	err := r.db.FanOut(func(shard int, db *sqlx.DB) error {
+		return db.Select(&keys[shard], `SELECT key FROM items ORDER BY key`)
+	})
+	return keys, err
+}
+
+func (r shardRepo) Keys(ctx context.Context, dest interface{}) error {
+	return r.db.Select(ctx, dest, `SELECT key FROM items ORDER BY key`)
+}
+
+func (r shardRepo) Fail(errs ...error) error {
+	return r.db.FanOut(func(shard int, _ *sqlx.DB) error {
+		return errs[shard]
+	})
+}
+
+// newShardedSQLite returns ShardedDB with two shards, keys less than "m"
+// are stored in the first shard.
+func newShardedSQLite(t *testing.T, cfg sql.Config) shardRepo {
+	t.Helper()
+	sqltest.RegisterSQLite()
+
+	db, err := sql.NewSharded(context.Background(), sqltest.SQLiteDriverName, cfg, sql.RangeMap{Bounds: []string{"m"}},
+		sqltest.SQLiteMemory{Name: t.Name() + "/0"},
+		sqltest.SQLiteMemory{Name: t.Name() + "/1"},
+	)
+	if err != nil {
+		t.Fatalf("sql.NewSharded: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := shardRepo{db: db}
+	err = repo.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %s", err)
+	}
+
+	return repo
+}
+
+func TestShardedDB_Routing(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	repo := newShardedSQLite(t, sql.Config{})
+	r.NoError(repo.AddNoTx("a"))
+	r.NoError(repo.AddTx(ctx, "b"))
+	r.NoError(repo.AddNoTx("y"))
+	r.NoError(repo.AddTx(ctx, "z"))
+
+	keys, err := repo.KeysByShard()
+	r.NoError(err)
+	r.Equal([][]string{{"a", "b"}, {"y", "z"}}, keys)
+}
+
+func TestShardedDB_Select(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	repo := newShardedSQLite(t, sql.Config{})
+	for _, key := range []string{"z", "b", "a", "y"} {
+		r.NoError(repo.AddNoTx(key))
+	}
+
+	var keys []string
+	r.NoError(repo.Keys(ctx, &keys))
+	r.Equal([]string{"a", "b", "y", "z"}, keys)
+
+	keys = []string{"x"}
+	r.NoError(repo.Keys(ctx, &keys))
+	r.Equal([]string{"x", "a", "b", "y", "z"}, keys)
+
+	r.ErrorIs(repo.Keys(ctx, keys), sql.ErrInvalidDest)
+	r.ErrorIs(repo.Keys(ctx, new(string)), sql.ErrInvalidDest)
+	r.ErrorIs(repo.Keys(ctx, (*[]string)(nil)), sql.ErrInvalidDest)
+}
+
+func TestShardedDB_FanOut(t *testing.T) {
+	t.Parallel()
+
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	tests := map[string]struct {
+		errs    []error
+		wantErr error
+		wantMsg string
+	}{
+		"ok":     {[]error{nil, nil}, nil, ""},
+		"second": {[]error{nil, errSecond}, errSecond, "shard 1: Fail: second"},
+		"both":   {[]error{errFirst, errSecond}, errFirst, "shard 0: Fail: first"},
+	}
+
+	repo := newShardedSQLite(t, sql.Config{ReturnErrs: []error{errFirst, errSecond}})
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			err := repo.Fail(tc.errs...)
+			r.ErrorIs(err, tc.wantErr)
+			if tc.wantErr != nil {
+				r.EqualError(err, tc.wantMsg)
+			}
+		})
+	}
+}
+
+func TestShardedDB_Metrics(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	collector := &callsCollector{}
+	repo := newShardedSQLite(t, sql.Config{Metrics: collector})
+	collector.calls = nil
+
+	r.NoError(repo.AddNoTx("a"))
+	r.NoError(repo.AddTx(ctx, "z"))
+	r.Equal([]string{"AddNoTx:0", "AddTx:1"}, collector.calls)
+
+	collector.calls = nil
+	r.NoError(repo.Keys(ctx, new([]string)))
+	r.ElementsMatch([]string{"Keys:0", "Keys:1"}, collector.calls)
+}
+
+func TestNewSharded(t *testing.T) {
+	t.Parallel()
+
+	sqltest.RegisterSQLite()
+	ctx := context.Background()
+	conn := sqltest.SQLiteMemory{}
+	tests := map[string]struct {
+		strategy   sql.ShardStrategy
+		connectors []sql.Connector
+		wantErr    error
+	}{
+		"no_shards":   {sql.HashMod{}, nil, sql.ErrNoShards},
+		"no_strategy": {nil, []sql.Connector{conn}, sql.ErrNoShardStrategy},
+		"range_map":   {sql.RangeMap{}, []sql.Connector{conn, conn}, sql.ErrInvalidRangeMap},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			db, err := sql.NewSharded(ctx, sqltest.SQLiteDriverName, sql.Config{}, tc.strategy, tc.connectors...)
+			r.ErrorIs(err, tc.wantErr)
+			r.Nil(db)
+		})
+	}
+}
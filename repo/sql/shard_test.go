@@ -0,0 +1,111 @@
+package sql_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+func TestShardStrategy(t *testing.T) {
+	t.Parallel()
+
+	const shards = 4
+
+	consistentHash, err := sql.NewConsistentHash(0)
+	require.NoError(t, err)
+
+	testCases := map[string]sql.ShardStrategy{
+		"hash_mod":             sql.HashMod{},
+		"consistent_hash":      consistentHash,
+		"consistent_hash_zero": &sql.ConsistentHash{},
+		"range_map":            sql.RangeMap{Bounds: []string{"2", "5", "7"}},
+	}
+
+	for name, strategy := range testCases {
+		name, strategy := name, strategy
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			used := make(map[int]bool)
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa(i)
+				shard, err := strategy.Shard(key, shards)
+				r.NoError(err)
+				r.True(shard >= 0 && shard < shards)
+				again, err := strategy.Shard(key, shards)
+				r.NoError(err)
+				r.Equal(shard, again)
+				used[shard] = true
+			}
+			r.Len(used, shards)
+
+			_, err := strategy.Shard("key", 0)
+			r.ErrorIs(err, sql.ErrNoShards)
+		})
+	}
+}
+
+func TestConsistentHash_AddShard(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	const keys = 10000
+	strategy, err := sql.NewConsistentHash(0)
+	r.NoError(err)
+
+	moved := 0
+	for i := 0; i < keys; i++ {
+		key := strconv.Itoa(i)
+		before, err := strategy.Shard(key, 4)
+		r.NoError(err)
+		after, err := strategy.Shard(key, 5)
+		r.NoError(err)
+		if before != after {
+			r.Equal(4, after)
+			moved++
+		}
+	}
+	r.Less(moved, keys/3)
+}
+
+func TestNewConsistentHash(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	_, err := sql.NewConsistentHash(-1)
+	r.ErrorIs(err, sql.ErrInvalidReplicas)
+}
+
+func TestRangeMap_Shard(t *testing.T) {
+	t.Parallel()
+
+	strategy := sql.RangeMap{Bounds: []string{"g", "p"}}
+
+	tests := []struct {
+		given string
+		want  int
+	}{
+		{"", 0},
+		{"a", 0},
+		{"f", 0},
+		{"g", 1},
+		{"o", 1},
+		{"p", 2},
+		{"z", 2},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.given, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			shard, err := strategy.Shard(tc.given, 3)
+			r.NoError(err)
+			r.Equal(tc.want, shard)
+		})
+	}
+}
@@ -3,8 +3,11 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"log"
+	"runtime/debug"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -29,12 +32,44 @@ type Config struct {
 	SetConnMaxIdleTime    time.Duration
 	SetMaxOpenConnections int
 	SetMaxIdleConnections int
+
+	// SessionVariables is a registry of variable names which may be
+	// passed to DB.Tx by WithSessionVariable.
+	SessionVariables []string
+	// Tenants is a registry of tenants which data is stored in separate schemas.
+	Tenants TenantRegistry
+	// TenantMetrics adds LabelTenant to metrics, Metrics must support it.
+	TenantMetrics bool
+	// StrictPolicy defines how to handle errors which are actually programming bugs.
+	StrictPolicy StrictPolicy
+	// OnProgrammingBug is required for StrictReport policy.
+	OnProgrammingBug func(*BugError)
+	// Logger is log.Default() by default.
+	Logger Logger
+	// TxMetrics collects metrics of transactions, see NewTxMetrics.
+	TxMetrics *TxMetrics
+	// LongTxThreshold enables logging a warning with captured stack
+	// for transactions which are open longer.
+	LongTxThreshold time.Duration
+	// QueryTags enables sqlcommenter-style comments for every statement.
+	QueryTags *QueryTags
+	// Queries are validated against database by New (see DB.Validate).
+	Queries *Queries
+	// StmtCacheSize enables LRU cache of prepared statements (see DB.Stmt).
+	StmtCacheSize int
+	// StmtCacheMetrics collects metrics of prepared statement cache, see NewStmtCacheMetrics.
+	StmtCacheMetrics *StmtCacheMetrics
+	// Chaos injects faults into DAL method calls, it's for testing only.
+	Chaos *Chaos
 }
 
 func (c Config) setDefault() Config {
 	if c.Metrics == nil {
 		c.Metrics = repo.NoMetric{}
 	}
+	if c.Logger == nil {
+		c.Logger = log.Default()
+	}
 	if c.SetConnMaxLifetime == 0 {
 		c.SetConnMaxLifetime = DefaultSetConnMaxLifetime
 	}
@@ -56,17 +91,55 @@ type Connector interface {
 	DSN() (string, error)
 }
 
+// Validator is implemented by Connector which can check config,
+// New calls it before opening connection pool.
+type Validator interface {
+	// Validate returns error if config can't be used for connecting.
+	Validate() error
+}
+
 // DB is a wrapper for sql database.
 type DB struct {
-	conn       *sqlx.DB
-	returnErrs []error
-	metrics    repo.MetricCollector
+	conn          *sqlx.DB
+	returnErrs    []error
+	metrics       repo.MetricCollector
+	sessionVars   map[string]struct{}
+	tenants       TenantRegistry
+	tenantMetrics bool
+	strictPolicy  StrictPolicy
+	onBug         func(*BugError)
+	logger        Logger
+	txs           *txTracker
+	queries       *Queries
+	stmts         *stmtCache
+	chaos         *Chaos
 }
 
 // New build and returns new DB.
 func New(ctx context.Context, driver string, cfg Config, connector Connector) (*DB, error) {
 	cfg = cfg.setDefault()
 
+	sessionVars, err := newSessionRegistry(cfg.SessionVariables)
+	if err != nil {
+		return nil, fmt.Errorf("newSessionRegistry: %w", err)
+	}
+
+	if cfg.StrictPolicy == StrictReport && cfg.OnProgrammingBug == nil {
+		return nil, ErrNoBugReporter
+	}
+
+	err = cfg.Tenants.validate()
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Tenants.validate: %w", err)
+	}
+
+	if validator, ok := connector.(Validator); ok {
+		err = validator.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("connector.Validate: %w", err)
+		}
+	}
+
 	dsn, err := connector.DSN()
 	if err != nil {
 		return nil, fmt.Errorf("connector.DSN: %w", err)
@@ -77,6 +150,16 @@ func New(ctx context.Context, driver string, cfg Config, connector Connector) (*
 		return nil, fmt.Errorf("sql.Open: %w", err)
 	}
 
+	wrapped, err := openConnector(conn.Driver(), dsn, cfg.QueryTags)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("openConnector: %w", err)
+	}
+	if wrapped != nil {
+		_ = conn.Close()
+		conn = sql.OpenDB(wrapped)
+	}
+
 	err = conn.PingContext(ctx)
 	for err != nil {
 		nextErr := conn.PingContext(ctx)
@@ -87,9 +170,21 @@ func New(ctx context.Context, driver string, cfg Config, connector Connector) (*
 	}
 
 	db := &DB{
-		conn:       sqlx.NewDb(conn, driver),
-		returnErrs: cfg.ReturnErrs,
-		metrics:    cfg.Metrics,
+		conn:          sqlx.NewDb(conn, driver),
+		returnErrs:    cfg.ReturnErrs,
+		metrics:       cfg.Metrics,
+		sessionVars:   sessionVars,
+		tenants:       cfg.Tenants,
+		tenantMetrics: cfg.TenantMetrics,
+		strictPolicy:  cfg.StrictPolicy,
+		onBug:         cfg.OnProgrammingBug,
+		logger:        cfg.Logger,
+		txs:           newTxTracker(cfg.TxMetrics, cfg.LongTxThreshold, cfg.Logger),
+		queries:       cfg.Queries,
+		chaos:         cfg.Chaos,
+	}
+	if cfg.StmtCacheSize > 0 {
+		db.stmts = newStmtCache(cfg.StmtCacheSize, cfg.StmtCacheMetrics)
 	}
 
 	db.conn.SetConnMaxLifetime(cfg.SetConnMaxLifetime)
@@ -97,45 +192,132 @@ func New(ctx context.Context, driver string, cfg Config, connector Connector) (*
 	db.conn.SetMaxOpenConns(cfg.SetMaxOpenConnections)
 	db.conn.SetMaxIdleConns(cfg.SetMaxIdleConnections)
 
+	if db.queries != nil {
+		err = db.Validate(ctx)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("db.Validate: %w", err)
+		}
+	}
+
 	return db, nil
 }
 
-// Turn sqlx errors like `missing destination …` into panics
-// https://github.com/jmoiron/sqlx/issues/529. As we can't distinguish
+// Turn sqlx errors like `missing destination …` into panics (or other
+// StrictPolicy) https://github.com/jmoiron/sqlx/issues/529. As we can't distinguish
 // between sqlx and other errors except driver ones, let's hope filtering
 // driver errors is enough and there are no other non-driver regular errors.
-func (db *DB) strict(err error) error {
+func (db *DB) strict(methodName string, err error) error {
 	switch {
 	case err == nil:
+	case errors.Is(err, ErrProgrammingBug): // Already handled, e.g. by DB.TxStmt inside DB.Tx.
 	case errors.As(err, new(*pq.Error)):
+	case isDriverError(err):
+	case errors.Is(err, driver.ErrBadConn):
 	case errors.Is(err, sql.ErrNoRows):
 	case errors.Is(err, context.Canceled):
 	case errors.Is(err, context.DeadlineExceeded):
+	case errors.Is(err, ErrUnknownSessionVariable):
+	case errors.Is(err, ErrUnknownTenant):
+	case errors.Is(err, ErrTenantNoTx):
+	case errors.Is(err, ErrNoSuitableHost):
 	default:
 		for i := range db.returnErrs {
 			if errors.Is(err, db.returnErrs[i]) {
 				return err
 			}
 		}
-		panic(err)
+		return db.bug(methodName, err)
 	}
 	return err
 }
 
+// bug handles error which is actually programming bug according to StrictPolicy.
+func (db *DB) bug(methodName string, err error) error {
+	bugErr := &BugError{Err: err, Stack: debug.Stack()}
+
+	if collector, ok := db.metrics.(repo.BugCollector); ok {
+		collector.Bug(methodName)
+	}
+	db.logger.Printf("%s\n%s", bugErr, bugErr.Stack)
+
+	switch db.strictPolicy {
+	case StrictPanic:
+		panic(err)
+	case StrictReport:
+		db.onBug(bugErr)
+	case StrictError:
+	}
+
+	return bugErr
+}
+
+// collecting collects metrics for DAL method with additional labels
+// if metric collector supports them.
+func (db *DB) collecting(methodName string, labels map[string]string, f func() error) func() error {
+	if collector, ok := db.metrics.(repo.LabeledMetricCollector); ok && len(labels) != 0 {
+		return collector.CollectingWith(methodName, labels, f)
+	}
+
+	return db.metrics.Collecting(methodName, f)
+}
+
 // Close implements io.Closer.
 func (db *DB) Close() error {
+	db.txs.close()
+	if db.stmts != nil {
+		db.stmts.invalidate()
+	}
 	return db.conn.Close()
 }
 
+// Stats returns connection pool statistics.
+func (db *DB) Stats() sql.DBStats {
+	return db.conn.Stats()
+}
+
+// OpenTx returns currently open transactions, it's useful for
+// finding leaked transactions.
+func (db *DB) OpenTx() []OpenTx {
+	return db.txs.openTx()
+}
+
 // NoTx provides DAL method wrapper with:
 // - converting sqlx errors which are actually bugs into panics,
 // - general metrics for DAL methods,
 // - wrapping errors with DAL method name.
 func (db *DB) NoTx(f func(*sqlx.DB) error) (err error) {
-	methodName := reflectx.CallerMethodName(1)
-	return db.strict(db.metrics.Collecting(methodName, func() error {
-		err := f(db.conn)
+	return db.noTx(context.Background(), reflectx.CallerMethodName(1), nil, func(_ context.Context, conn *sqlx.DB) error {
+		return f(conn)
+	})
+}
+
+// NoTxContext provides the same DAL method wrapper as NoTx, but ctx passed
+// to f contains DAL method name for query tags (see Config.QueryTags),
+// so statements should be executed with it. Tenant can't be used with
+// it (see WithTenant).
+func (db *DB) NoTxContext(ctx context.Context, f func(context.Context, *sqlx.DB) error) (err error) {
+	return db.noTx(ctx, reflectx.CallerMethodName(1), nil, f)
+}
+
+func (db *DB) noTx(ctx context.Context, methodName string, labels map[string]string, f func(context.Context, *sqlx.DB) error) (err error) {
+	ctx = withMethodName(ctx, methodName)
+	return db.strict(methodName, db.collecting(methodName, labels, func() error {
+		var err error
+		if _, ok := Tenant(ctx); ok {
+			err = ErrTenantNoTx
+		}
+		if err == nil {
+			err = db.chaos.inject(ctx, methodName)
+			if errors.Is(err, driver.ErrBadConn) {
+				db.dropPoolConn(ctx)
+			}
+		}
+		if err == nil {
+			err = f(ctx, db.conn)
+		}
 		if err != nil {
+			db.stmts.invalidateOn(err)
 			err = fmt.Errorf("%s: %w", methodName, err)
 		}
 		return err
@@ -146,28 +328,155 @@ func (db *DB) NoTx(f func(*sqlx.DB) error) (err error) {
 // - converting sqlx errors which are actually bugs into panics,
 // - general metrics for DAL methods,
 // - wrapping errors with DAL method name,
-// - transaction.
+// - transaction,
+// - session variables from ctx (see WithSessionVariable),
+// - tenant's search_path from ctx (see WithTenant),
+// - CockroachDB options from ctx (see WithCockroachTxOptions),
+// - post-commit and post-rollback hooks (see OnCommit and OnRollback),
+// - tracking of long-running transactions (see Config.TxMetrics and Config.LongTxThreshold),
+// - DAL method name in query tags (see Config.QueryTags).
 func (db *DB) Tx(ctx context.Context, opts *sql.TxOptions, f func(*sqlx.Tx) error) (err error) {
-	methodName := reflectx.CallerMethodName(1)
-	return db.strict(db.metrics.Collecting(methodName, func() error {
-		tx, err := db.conn.BeginTxx(ctx, opts)
+	return db.tx(ctx, reflectx.CallerMethodName(1), nil, opts, f)
+}
+
+func (db *DB) tx(ctx context.Context, methodName string, labels map[string]string, opts *sql.TxOptions, f func(*sqlx.Tx) error) (err error) {
+	ctx = withMethodName(ctx, methodName)
+	return db.strict(methodName, db.collecting(methodName, db.tenantLabels(ctx, labels), func() error {
+		conn, tx, err := db.beginTx(ctx, db.txOptions(ctx, opts))
+		if conn != nil {
+			defer func() {
+				if errors.Is(err, driver.ErrBadConn) {
+					dropConn(conn)
+				}
+				_ = conn.Close()
+			}()
+		}
 		if err == nil { //nolint:nestif // No idea how to simplify.
+			// Hooks run after transaction is finished, so they aren't tracked.
+			done := db.txs.track(tx, methodName)
+			defer done()
+			hooks := db.registerHooks(tx, methodName)
+			defer unregisterHooks(tx)
 			defer func() {
 				if err := recover(); err != nil {
-					if errRollback := tx.Rollback(); errRollback != nil {
+					errRollback := tx.Rollback()
+					done()
+					hooks.runOnRollback(hooks.onRollback, rollbackErr(err))
+					if errRollback != nil {
 						err = fmt.Errorf("%v: %s", err, errRollback)
 					}
 					panic(err)
 				}
 			}()
-			err = f(tx)
+			err = db.setCockroachTx(ctx, tx)
+			if err == nil {
+				err = db.chaos.inject(ctx, methodName)
+			}
+			if err == nil {
+				err = db.setSessionVariables(ctx, tx)
+			}
+			if err == nil {
+				err = db.setTenantTx(ctx, tx)
+			}
+			if err == nil {
+				err = f(tx)
+			}
 			if err == nil {
 				err = tx.Commit()
-			} else if errRollback := tx.Rollback(); errRollback != nil {
-				err = fmt.Errorf("%v: %s", err, errRollback)
+				done()
+				if err == nil {
+					hooks.runOnCommit()
+				} else {
+					hooks.runOnRollback(hooks.onRollback, err)
+				}
+			} else {
+				errRollback := tx.Rollback()
+				done()
+				hooks.runOnRollback(hooks.onRollback, err)
+				if errRollback != nil {
+					err = fmt.Errorf("%v: %s", err, errRollback)
+				}
+			}
+		}
+		if err != nil {
+			db.stmts.invalidateOn(err)
+			err = fmt.Errorf("%s: %w", methodName, err)
+		}
+		return err
+	})())
+}
+
+// beginTx starts transaction, it's started on pinned connection if Chaos
+// is used, so FaultDropConnection can drop connection of transaction.
+func (db *DB) beginTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Conn, *sqlx.Tx, error) {
+	if db.chaos == nil {
+		tx, err := db.conn.BeginTxx(ctx, opts)
+		return nil, tx, err
+	}
+
+	conn, err := db.conn.Connx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := conn.BeginTxx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, tx, nil
+}
+
+// connSetter applies connection settings from ctx and returns func
+// for resetting them or nil if there is nothing to reset.
+type connSetter func(context.Context, *sqlx.Conn) (reset func() error, err error)
+
+// Conn provides DAL method wrapper like NoTx, but with a connection pinned
+// for the duration of the call, which makes it possible to apply
+// connection settings from ctx, they're reset before connection is released:
+// - tenant's search_path (see WithTenant),
+// - follower reads (see WithFollowerReads),
+// - DAL method name in query tags (see Config.QueryTags).
+func (db *DB) Conn(ctx context.Context, f func(*sqlx.Conn) error) (err error) {
+	return db.withConn(ctx, reflectx.CallerMethodName(1), nil, f)
+}
+
+func (db *DB) withConn(ctx context.Context, methodName string, labels map[string]string, f func(*sqlx.Conn) error) (err error) {
+	ctx = withMethodName(ctx, methodName)
+	return db.strict(methodName, db.collecting(methodName, db.tenantLabels(ctx, labels), func() (err error) {
+		conn, err := db.conn.Connx(ctx)
+		if err == nil {
+			defer conn.Close()
+			tagConn(ctx, conn)
+
+			var resets []func() error
+			defer func() {
+				for i := len(resets) - 1; i >= 0; i-- {
+					if errReset := resets[i](); err == nil {
+						err = errReset
+					}
+				}
+			}()
+			err = db.chaos.inject(ctx, methodName)
+			if errors.Is(err, driver.ErrBadConn) {
+				dropConn(conn)
+			}
+			for _, set := range []connSetter{db.setTenantConn, db.setFollowerReadsConn} {
+				if err != nil {
+					break
+				}
+				var reset func() error
+				reset, err = set(ctx, conn)
+				if reset != nil {
+					resets = append(resets, reset)
+				}
+			}
+			if err == nil {
+				err = f(conn)
 			}
 		}
 		if err != nil {
+			db.stmts.invalidateOn(err)
 			err = fmt.Errorf("%s: %w", methodName, err)
 		}
 		return err
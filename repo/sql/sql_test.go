@@ -0,0 +1,78 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/connectors"
+)
+
+func TestNew_Validate(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	db, err := sql.New(context.Background(), "postgres", sql.Config{}, connectors.CockroachDB{})
+	r.Nil(db)
+	r.ErrorIs(err, connectors.ErrInvalidConfig)
+	r.Contains(err.Error(), "user: is required; host: is required if hosts and directory_path aren't set")
+}
+
+type testRepo struct {
+	db *sql.DB
+}
+
+func (r testRepo) NoTxMethod(ctx context.Context) (n int, err error) {
+	err = r.db.NoTxContext(ctx, func(ctx context.Context, db *sqlx.DB) error {
+		return db.GetContext(ctx, &n, "SELECT 1")
+	})
+	return n, err
+}
+
+func (r testRepo) TxMethod(ctx context.Context) (n int, err error) {
+	err = r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &n, "SELECT 2")
+	})
+	return n, err
+}
+
+func (r testRepo) ConnMethod(ctx context.Context) (n int, err error) {
+	err = r.db.Conn(ctx, func(conn *sqlx.Conn) error {
+		return conn.GetContext(ctx, &n, "SELECT 3")
+	})
+	return n, err
+}
+
+func TestDB_QueryTags(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	db, recording := newRecordingSQLite(t, sql.Config{QueryTags: &sql.QueryTags{Service: "users"}})
+	repo := testRepo{db: db}
+
+	for _, method := range []func(context.Context) (int, error){repo.NoTxMethod, repo.TxMethod, repo.ConnMethod} {
+		_, err := method(ctx)
+		r.NoError(err)
+	}
+
+	r.Equal([]string{
+		"SELECT 1 /*method='NoTxMethod',service='users'*/",
+		"SELECT 2 /*method='TxMethod',service='users'*/",
+		"SELECT 3 /*method='ConnMethod',service='users'*/",
+	}, recording.all())
+}
+
+func TestDB_NoTxContext_Tenant(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := sql.WithTenant(context.Background(), "tenant")
+
+	db, recording := newRecordingSQLite(t, sql.Config{Tenants: sql.TenantRegistry{"tenant": "tenant"}})
+	_, err := testRepo{db: db}.NoTxMethod(ctx)
+	r.ErrorIs(err, sql.ErrTenantNoTx)
+	r.Empty(recording.all())
+}
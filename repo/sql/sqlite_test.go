@@ -0,0 +1,180 @@
+package sql_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+// recordingDriverName is a name of SQLite driver which records statements
+// received by connections, so tests can check SQL sent to database.
+// Unlike modernc.org/sqlite, which compiles statement on the first use,
+// it compiles statement on Prepare like PostgreSQL does.
+const recordingDriverName = "sqlite-recording"
+
+var (
+	registerRecording sync.Once
+	recordings        sync.Map // DSN -> *recording.
+)
+
+// recording contains statements received by connections to one database.
+type recording struct {
+	mu         sync.Mutex
+	statements []string
+	opened     int
+}
+
+func (r *recording) open() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opened++
+}
+
+// connections returns amount of opened connections.
+func (r *recording) connections() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.opened
+}
+
+func (r *recording) add(query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, query)
+}
+
+// all returns received statements.
+func (r *recording) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.statements...)
+}
+
+// reset forgets received statements.
+func (r *recording) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = nil
+}
+
+type recordingDriver struct {
+	driver driver.Driver
+}
+
+func (d recordingDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	r, _ := recordings.LoadOrStore(dsn, &recording{})
+	r.(*recording).open()
+	return &recordingConn{Conn: conn, recording: r.(*recording)}, nil
+}
+
+var (
+	_ driver.ConnBeginTx        = (*recordingConn)(nil)
+	_ driver.ConnPrepareContext = (*recordingConn)(nil)
+	_ driver.ExecerContext      = (*recordingConn)(nil)
+	_ driver.QueryerContext     = (*recordingConn)(nil)
+)
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+type recordingConn struct {
+	driver.Conn
+	recording *recording
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *recordingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.recording.add(query)
+	var args []driver.NamedValue // NULL for every $n parameter.
+	for _, m := range placeholder.FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(m[1])
+		for len(args) < n {
+			args = append(args, driver.NamedValue{Ordinal: len(args) + 1})
+		}
+	}
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, "EXPLAIN "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.recording.add(query)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.recording.add(query)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+// newRecordingSQLite returns DB connected to new in-memory SQLite database
+// and statements received by its connections.
+func newRecordingSQLite(t *testing.T, cfg sql.Config) (*sql.DB, *recording) {
+	t.Helper()
+	sqltest.RegisterSQLite()
+	registerRecording.Do(func() {
+		db, err := stdsql.Open(sqltest.SQLiteDriverName, "")
+		if err != nil {
+			t.Fatalf("sql.Open: %s", err)
+		}
+		stdsql.Register(recordingDriverName, recordingDriver{driver: db.Driver()})
+		_ = db.Close()
+		sqlx.BindDriver(recordingDriverName, sqlx.DOLLAR)
+	})
+
+	conn := sqltest.SQLiteMemory{Name: t.Name()}
+	dsn, err := conn.DSN()
+	if err != nil {
+		t.Fatalf("SQLiteMemory.DSN: %s", err)
+	}
+
+	db, err := sql.New(context.Background(), recordingDriverName, cfg, conn)
+	if err != nil {
+		t.Fatalf("sql.New: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+		recordings.Delete(dsn)
+	})
+
+	r, _ := recordings.LoadOrStore(dsn, &recording{})
+	return db, r.(*recording)
+}
+
+// callsCollector records DAL method and shard label (see sql.LabelShard) of every call.
+type callsCollector struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *callsCollector) Collecting(method string, f func() error) func() error {
+	return c.CollectingWith(method, nil, f)
+}
+
+func (c *callsCollector) CollectingWith(method string, labels map[string]string, f func() error) func() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, method+":"+labels[sql.LabelShard])
+
+	return f
+}
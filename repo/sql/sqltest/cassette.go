@@ -0,0 +1,473 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+// EnvMode is an environment variable which sets mode for NewCassette,
+// e.g. SQLTEST_MODE=record go test ./...
+const EnvMode = "SQLTEST_MODE"
+
+// Errors.
+var (
+	ErrNoSession       = errors.New("no session")
+	ErrUnexpectedQuery = errors.New("unexpected query")
+	ErrUnknownMode     = errors.New("unknown mode")
+	ErrUnsupportedDSN  = errors.New("unsupported DSN")
+)
+
+// ReplayedError is a recorded driver error which isn't pq.Error. It's
+// registered by sql.RegisterDriverError, so replayed error isn't a
+// programming bug for sql.DB like the recorded one.
+type ReplayedError struct {
+	// Type is a type of recorded error, e.g. *sqlite.Error.
+	Type    string
+	Message string
+}
+
+// Error implements error.
+func (e *ReplayedError) Error() string { return e.Message }
+
+// Mode is a mode of Cassette.
+type Mode uint8
+
+// Enum.
+const (
+	_          Mode = iota
+	ModeReplay      // replay
+	ModeRecord      // record
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *Mode) UnmarshalText(str []byte) error {
+	switch string(str) {
+	case ModeReplay.String():
+		*i = ModeReplay
+	case ModeRecord.String():
+		*i = ModeRecord
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownMode, str)
+	}
+
+	return nil
+}
+
+var _ sql.Connector = Cassette{}
+
+// Cassette is a connector for DriverName driver. In ModeRecord it proxies
+// every statement to real database and records statement, its arguments and
+// result, the file is written when the last connection is closed or on
+// NewCassette cleanup. Cassette must be created by NewCassette, Path and Mode
+// may be changed after it. In ModeReplay it serves results from the file without
+// any database and fails on unexpected statements.
+type Cassette struct {
+	Path string
+	Mode Mode
+	// Driver and Connector are used for connecting to real database in ModeRecord.
+	Driver    string
+	Connector sql.Connector
+
+	session string
+}
+
+// NewCassette returns Cassette for the test which is stored in
+// testdata/cassettes/<test name>.json. Mode is set by EnvMode variable,
+// ModeReplay is used by default. In ModeRecord file is written on test
+// cleanup, in ModeReplay test fails if some recorded statements weren't executed.
+func NewCassette(t testing.TB, driverName string, conn sql.Connector) Cassette {
+	t.Helper()
+
+	mode := ModeReplay
+	if env := os.Getenv(EnvMode); env != "" {
+		err := mode.UnmarshalText([]byte(env))
+		if err != nil {
+			t.Fatalf("%s: %s", EnvMode, err)
+		}
+	}
+
+	c := Cassette{
+		Path:      filepath.Join("testdata", "cassettes", filepath.FromSlash(t.Name())+".json"),
+		Mode:      mode,
+		Driver:    driverName,
+		Connector: conn,
+		session:   newSessionID(),
+	}
+
+	t.Cleanup(func() {
+		s := closeSession(c.session)
+		if s == nil {
+			return
+		}
+
+		err := s.save()
+		if err != nil {
+			t.Errorf("%s: %s", c.Path, err)
+		}
+		if s.mode != ModeReplay {
+			return
+		}
+		for _, i := range s.unused() {
+			t.Errorf("%s: statement wasn't executed: %s", c.Path, i.Query)
+		}
+	})
+
+	return c
+}
+
+// DSN implements sql.Connector.
+func (c Cassette) DSN() (string, error) {
+	values := url.Values{}
+	values.Set("path", c.Path)
+	values.Set("mode", c.Mode.String())
+
+	if c.session == "" {
+		return "", fmt.Errorf("%w: use NewCassette", ErrNoSession)
+	}
+	values.Set("session", c.session)
+
+	if c.Mode == ModeRecord {
+		if c.Connector == nil {
+			return "", fmt.Errorf("%w: no connector for record mode", ErrUnknownMode)
+		}
+
+		dsn, err := c.Connector.DSN()
+		if err != nil {
+			return "", fmt.Errorf("c.Connector.DSN: %w", err)
+		}
+		values.Set("driver", c.Driver)
+		values.Set("dsn", dsn)
+	}
+
+	return values.Encode(), nil
+}
+
+var lastSessionID uint64
+
+func newSessionID() string {
+	return strconv.FormatUint(atomic.AddUint64(&lastSessionID, 1), 10)
+}
+
+type (
+	// interaction is a recorded statement with its result.
+	interaction struct {
+		Exec    bool      `json:"exec,omitempty"`
+		Query   string    `json:"query"`
+		Args    []value   `json:"args,omitempty"`
+		Columns []string  `json:"columns,omitempty"`
+		Rows    [][]value `json:"rows,omitempty"`
+		Result  *result   `json:"result,omitempty"`
+		Err     *recorded `json:"error,omitempty"`
+
+		used bool
+	}
+
+	result struct {
+		LastInsertID    int64     `json:"last_insert_id"`
+		LastInsertIDErr *recorded `json:"last_insert_id_error,omitempty"`
+		RowsAffected    int64     `json:"rows_affected"`
+		RowsAffectedErr *recorded `json:"rows_affected_error,omitempty"`
+	}
+
+	// recorded is a recorded error, pq.Error is recorded with all fields,
+	// so repository code can handle it like real one.
+	recorded struct {
+		PQ      *pq.Error `json:"pq,omitempty"`
+		Type    string    `json:"type,omitempty"`
+		Message string    `json:"message,omitempty"`
+	}
+
+	// value is a driver.Value with its type.
+	value struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value,omitempty"`
+	}
+)
+
+func recordErr(err error) *recorded {
+	if err == nil {
+		return nil
+	}
+
+	pqErr := new(pq.Error)
+	if errors.As(err, &pqErr) {
+		return &recorded{PQ: pqErr}
+	}
+
+	return &recorded{Type: fmt.Sprintf("%T", err), Message: err.Error()}
+}
+
+func (r *recorded) err() error {
+	switch {
+	case r == nil:
+		return nil
+	case r.PQ != nil:
+		pqErr := *r.PQ
+		return &pqErr
+	case r.Message == context.Canceled.Error():
+		return context.Canceled
+	case r.Message == context.DeadlineExceeded.Error():
+		return context.DeadlineExceeded
+	default:
+		return &ReplayedError{Type: r.Type, Message: r.Message}
+	}
+}
+
+func encodeValue(v driver.Value) (value, error) {
+	var typ string
+	switch v.(type) {
+	case nil:
+		return value{Type: "null"}, nil
+	case int64:
+		typ = "int64"
+	case float64:
+		typ = "float64"
+	case bool:
+		typ = "bool"
+	case []byte:
+		typ = "bytes"
+	case string:
+		typ = "string"
+	case time.Time:
+		typ = "time"
+	default:
+		return value{}, fmt.Errorf("unsupported value type: %T", v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return value{}, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return value{Type: typ, Value: b}, nil
+}
+
+func (v value) decode() (driver.Value, error) {
+	var (
+		dest interface{}
+		err  error
+	)
+	switch v.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		var i int64
+		err, dest = json.Unmarshal(v.Value, &i), i
+	case "float64":
+		var f float64
+		err, dest = json.Unmarshal(v.Value, &f), f
+	case "bool":
+		var b bool
+		err, dest = json.Unmarshal(v.Value, &b), b
+	case "bytes":
+		var b []byte
+		err, dest = json.Unmarshal(v.Value, &b), b
+	case "string":
+		var s string
+		err, dest = json.Unmarshal(v.Value, &s), s
+	case "time":
+		var t time.Time
+		err, dest = json.Unmarshal(v.Value, &t), t
+	default:
+		return nil, fmt.Errorf("unsupported value type: %s", v.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return dest, nil
+}
+
+func encodeArgs(args []driver.NamedValue) ([]value, error) {
+	values := make([]value, len(args))
+	for i := range args {
+		var err error
+		values[i], err = encodeValue(args[i].Value)
+		if err != nil {
+			return nil, fmt.Errorf("arg %d: %w", args[i].Ordinal, err)
+		}
+	}
+
+	return values, nil
+}
+
+func equalArgs(a, b []value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || string(a[i].Value) != string(b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// session is a state of cassette shared by all connections of one sql.DB.
+type session struct {
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []*interaction
+	conns        int  // Open connections.
+	unsaved      bool // Interactions were recorded after the last save.
+}
+
+type cassetteFile struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+var sessions = struct {
+	sync.Mutex
+	m map[string]*session
+}{m: make(map[string]*session)}
+
+func openSession(id, path string, mode Mode) (*session, error) {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	if s, ok := sessions.m[id]; ok {
+		s.acquire()
+		return s, nil
+	}
+
+	s := &session{path: path, mode: mode}
+	if mode == ModeReplay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		var file cassetteFile
+		err = json.Unmarshal(b, &file)
+		if err != nil {
+			return nil, fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		s.interactions = file.Interactions
+	}
+	s.acquire()
+	sessions.m[id] = s
+
+	return s, nil
+}
+
+func closeSession(id string) *session {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	s := sessions.m[id]
+	delete(sessions.m, id)
+
+	return s
+}
+
+// acquire registers new connection of session.
+func (s *session) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conns++
+}
+
+// release unregisters connection, cassette is saved when the last one is closed.
+func (s *session) release() error {
+	s.mu.Lock()
+	s.conns--
+	last := s.conns == 0
+	s.mu.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	return s.save()
+}
+
+// record appends interaction, it's written to the file by save.
+func (s *session) record(i *interaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interactions = append(s.interactions, i)
+	s.unsaved = true
+}
+
+// save writes cassette file if there are unsaved interactions.
+func (s *session) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mode != ModeRecord || !s.unsaved {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(cassetteFile{Interactions: s.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	err = os.WriteFile(s.path, append(b, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	s.unsaved = false
+
+	return nil
+}
+
+// replay returns the first unused interaction for the statement.
+func (s *session) replay(exec bool, query string, args []value) (*interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range s.interactions {
+		if !i.used && i.Exec == exec && i.Query == query && equalArgs(i.Args, args) {
+			i.used = true
+			return i, nil
+		}
+	}
+
+	msgs := make([]string, len(args))
+	for i := range args {
+		msgs[i] = string(args[i].Value)
+	}
+
+	return nil, fmt.Errorf("%w: %s [%s]", ErrUnexpectedQuery, query, strings.Join(msgs, ", "))
+}
+
+func (s *session) unused() []*interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var unused []*interaction
+	for _, i := range s.interactions {
+		if !i.used {
+			unused = append(unused, i)
+		}
+	}
+
+	return unused
+}
@@ -0,0 +1,155 @@
+package sqltest
+
+import (
+	"context"
+	"crypto/rand"
+	stdsql "database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+// DBConfig contains optional settings for NewDB.
+type DBConfig struct {
+	// Driver is "postgres" by default.
+	Driver string
+	Config sql.Config
+	// Template is a name of already migrated database which is cloned
+	// instead of creating empty database. PostgreSQL only.
+	Template   string
+	Migrations []Migrations
+}
+
+const maxDatabaseName = 63 // PostgreSQL identifier length limit.
+
+var notIdent = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// dsnConnector is a connector with ready DSN.
+type dsnConnector string
+
+// DSN implements sql.Connector.
+func (c dsnConnector) DSN() (string, error) { return string(c), nil }
+
+// NewDB creates uniquely named database on the server of conn (PostgreSQL
+// or CockroachDB), applies migrations and returns DB connected to it.
+// Database is dropped on test cleanup, so tests may run in parallel.
+func NewDB(t testing.TB, conn sql.Connector, cfg DBConfig) *sql.DB {
+	t.Helper()
+
+	if cfg.Driver == "" {
+		cfg.Driver = "postgres"
+	}
+
+	ctx := context.Background()
+	dsn, err := conn.DSN()
+	if err != nil {
+		t.Fatalf("conn.DSN: %s", err)
+	}
+
+	admin, err := stdsql.Open(cfg.Driver, dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	name, err := databaseName(t.Name())
+	if err != nil {
+		t.Fatalf("databaseName: %s", err)
+	}
+	cockroach, err := isCockroachDB(ctx, admin)
+	if err != nil {
+		t.Fatalf("isCockroachDB: %s", err)
+	}
+	if cockroach && cfg.Template != "" {
+		t.Fatalf("CockroachDB doesn't support template databases")
+	}
+
+	query := "CREATE DATABASE " + pq.QuoteIdentifier(name)
+	if cfg.Template != "" {
+		query += " TEMPLATE " + pq.QuoteIdentifier(cfg.Template)
+	}
+	_, err = admin.ExecContext(ctx, query)
+	if err != nil {
+		t.Fatalf("create database: %s", err)
+	}
+	t.Cleanup(func() {
+		query := "DROP DATABASE IF EXISTS " + pq.QuoteIdentifier(name)
+		if cockroach {
+			query += " CASCADE"
+		}
+		_, err := admin.ExecContext(ctx, query)
+		if err != nil {
+			t.Errorf("drop database: %s", err)
+		}
+	})
+
+	dsn, err = withDatabase(dsn, name)
+	if err != nil {
+		t.Fatalf("withDatabase: %s", err)
+	}
+
+	db, err := sql.New(ctx, cfg.Driver, cfg.Config, dsnConnector(dsn))
+	if err != nil {
+		t.Fatalf("sql.New: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() }) // Cleanups are called in reverse order, so before drop.
+
+	for _, m := range cfg.Migrations {
+		err = m.Apply(ctx, db)
+		if err != nil {
+			t.Fatalf("Migrations.Apply: %s", err)
+		}
+	}
+
+	return db
+}
+
+// databaseName returns unique database name for the test.
+func databaseName(testName string) (string, error) {
+	var suffix [4]byte
+	_, err := rand.Read(suffix[:])
+	if err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+
+	name := notIdent.ReplaceAllString(strings.ToLower(testName), "_")
+	name = strings.Trim(name, "_")
+	maxLen := maxDatabaseName - len("test__") - hex.EncodedLen(len(suffix))
+	if len(name) > maxLen {
+		name = name[:maxLen]
+	}
+
+	return "test_" + name + "_" + hex.EncodeToString(suffix[:]), nil
+}
+
+// withDatabase returns URL DSN with other database.
+func withDatabase(dsn, database string) (string, error) {
+	uri, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("url.Parse: %w", err)
+	}
+	if uri.Scheme == "" {
+		return "", fmt.Errorf("%w: URL is required", ErrUnsupportedDSN)
+	}
+	uri.Path = "/" + database
+	uri.RawPath = ""
+
+	return uri.String(), nil
+}
+
+func isCockroachDB(ctx context.Context, db *stdsql.DB) (bool, error) {
+	var version string
+	err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(version, "CockroachDB"), nil
+}
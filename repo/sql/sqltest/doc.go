@@ -0,0 +1,3 @@
+// Package sqltest contains helpers for testing repositories without
+// starting real database.
+package sqltest
@@ -0,0 +1,486 @@
+package sqltest
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+// DriverName is a name of record-and-replay driver, use it with Cassette:
+//
+//	db, err := sql.New(ctx, sqltest.DriverName, cfg, sqltest.NewCassette(t, "postgres", conn))
+const DriverName = "sqltest"
+
+//nolint:gochecknoinits // Like other database drivers.
+func init() {
+	stdsql.Register(DriverName, Driver{})
+	sql.RegisterDriverError(func(err error) bool {
+		return errors.As(err, new(*ReplayedError)) || errors.Is(err, ErrUnexpectedQuery)
+	})
+}
+
+var (
+	_ driver.Driver             = Driver{}
+	_ driver.Conn               = &conn{}
+	_ driver.ConnBeginTx        = &conn{}
+	_ driver.ExecerContext      = &conn{}
+	_ driver.QueryerContext     = &conn{}
+	_ driver.Pinger             = &conn{}
+	_ driver.StmtExecContext    = &stmt{}
+	_ driver.StmtQueryContext   = &stmt{}
+	_ driver.Rows               = &rows{}
+	_ driver.Tx                 = tx{}
+	_ driver.Result             = execResult{}
+	_ driver.NamedValueChecker  = &conn{}
+	_ driver.ConnPrepareContext = &conn{}
+)
+
+// Driver is a record-and-replay driver, it accepts DSN built by Cassette.
+type Driver struct{}
+
+// Open implements driver.Driver.
+func (Driver) Open(dsn string) (driver.Conn, error) {
+	values, err := url.ParseQuery(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("url.ParseQuery: %w", err)
+	}
+
+	var mode Mode
+	err = mode.UnmarshalText([]byte(values.Get("mode")))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := openSession(values.Get("session"), values.Get("path"), mode)
+	if err != nil {
+		return nil, fmt.Errorf("openSession: %w", err)
+	}
+
+	c := &conn{session: s}
+	if mode == ModeRecord {
+		c.real, err = openReal(values.Get("driver"), values.Get("dsn"))
+		if err != nil {
+			_ = s.release()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func openReal(driverName, dsn string) (driver.Conn, error) {
+	db, err := stdsql.Open(driverName, "")
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %w", err)
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("OpenConnector: %w", err)
+		}
+		return connector.Connect(context.Background())
+	}
+
+	return d.Open(dsn)
+}
+
+// conn replays statements from session, in ModeRecord it executes them
+// using real connection before.
+type conn struct {
+	session *session
+	real    driver.Conn // Nil in ModeReplay.
+}
+
+// CheckNamedValue implements driver.NamedValueChecker, arguments are
+// converted to default driver values, so they can be recorded.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	return err
+}
+
+// Prepare implements driver.Conn.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+// Close implements driver.Conn.
+func (c *conn) Close() error {
+	if c.real != nil {
+		err := c.real.Close()
+		if err != nil {
+			_ = c.session.release()
+			return err
+		}
+	}
+
+	err := c.session.release()
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	return nil
+}
+
+// Begin implements driver.Conn.
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.real == nil {
+		return tx{}, nil
+	}
+
+	if beginner, ok := c.real.(driver.ConnBeginTx); ok {
+		realTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return tx{real: realTx}, nil
+	}
+
+	realTx, err := c.real.Begin() //nolint:staticcheck // Fallback for old drivers.
+	if err != nil {
+		return nil, err
+	}
+
+	return tx{real: realTx}, nil
+}
+
+// Ping implements driver.Pinger.
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.real.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	i, err := c.do(ctx, true, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Err != nil {
+		return nil, i.Err.err()
+	}
+
+	return execResult{i.Result}, nil
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	i, err := c.do(ctx, false, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if i.Columns == nil && i.Err != nil {
+		return nil, i.Err.err()
+	}
+
+	return newRows(i)
+}
+
+// do returns interaction for the statement: recorded using real connection
+// in ModeRecord or loaded from cassette in ModeReplay.
+func (c *conn) do(ctx context.Context, exec bool, query string, args []driver.NamedValue) (*interaction, error) {
+	values, err := encodeArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.real == nil {
+		return c.session.replay(exec, query, values)
+	}
+
+	i := &interaction{Exec: exec, Query: query, Args: values}
+	if exec {
+		err = c.recordExec(ctx, i, args)
+	} else {
+		err = c.recordQuery(ctx, i, args)
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil, err // Statement will be retried by database/sql.
+	}
+	if err != nil {
+		i.Err = recordErr(err)
+	}
+
+	c.session.record(i)
+
+	return i, nil
+}
+
+func (c *conn) recordExec(ctx context.Context, i *interaction, args []driver.NamedValue) error {
+	var (
+		res driver.Result
+		err error
+	)
+	if execer, ok := c.real.(driver.ExecerContext); ok {
+		res, err = execer.ExecContext(ctx, i.Query, args)
+	}
+	if res == nil && (err == nil || errors.Is(err, driver.ErrSkip)) {
+		err = c.realStmt(ctx, i.Query, func(s driver.Stmt) (err error) {
+			res, err = stmtExec(ctx, s, args)
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	i.Result = &result{}
+	i.Result.LastInsertID, err = res.LastInsertId()
+	i.Result.LastInsertIDErr = recordErr(err)
+	i.Result.RowsAffected, err = res.RowsAffected()
+	i.Result.RowsAffectedErr = recordErr(err)
+
+	return nil
+}
+
+func (c *conn) recordQuery(ctx context.Context, i *interaction, args []driver.NamedValue) error {
+	var (
+		realRows driver.Rows
+		err      error
+	)
+	if queryer, ok := c.real.(driver.QueryerContext); ok {
+		realRows, err = queryer.QueryContext(ctx, i.Query, args)
+	}
+	if realRows == nil && (err == nil || errors.Is(err, driver.ErrSkip)) {
+		err = c.realStmt(ctx, i.Query, func(s driver.Stmt) (err error) {
+			realRows, err = stmtQuery(ctx, s, args)
+			if err != nil {
+				return err
+			}
+			return readRows(i, realRows)
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	return readRows(i, realRows)
+}
+
+// realStmt calls f with statement prepared by real connection.
+func (c *conn) realStmt(ctx context.Context, query string, f func(driver.Stmt) error) error {
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if preparer, ok := c.real.(driver.ConnPrepareContext); ok {
+		s, err = preparer.PrepareContext(ctx, query)
+	} else {
+		s, err = c.real.Prepare(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return f(s)
+}
+
+func stmtExec(ctx context.Context, s driver.Stmt, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := s.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+
+	return s.Exec(namedToValues(args)) //nolint:staticcheck // Fallback for old drivers.
+}
+
+func stmtQuery(ctx context.Context, s driver.Stmt, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := s.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+
+	return s.Query(namedToValues(args)) //nolint:staticcheck // Fallback for old drivers.
+}
+
+// readRows reads all rows into interaction, error returned while reading
+// rows is recorded as interaction error.
+func readRows(i *interaction, realRows driver.Rows) error {
+	defer realRows.Close()
+
+	i.Columns = realRows.Columns()
+	dest := make([]driver.Value, len(i.Columns))
+	for {
+		err := realRows.Next(dest)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			i.Err = recordErr(err)
+			return nil
+		}
+
+		row := make([]value, len(dest))
+		for j := range dest {
+			row[j], err = encodeValue(dest[j])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", i.Columns[j], err)
+			}
+		}
+		i.Rows = append(i.Rows, row)
+	}
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i := range args {
+		values[i] = args[i].Value
+	}
+
+	return values
+}
+
+// stmt isn't prepared by real database, statement is executed by conn.
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+// Close implements driver.Stmt.
+func (*stmt) Close() error { return nil }
+
+// NumInput implements driver.Stmt.
+func (*stmt) NumInput() int { return -1 }
+
+// Exec implements driver.Stmt.
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+// Query implements driver.Stmt.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func valuesToNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: args[i]}
+	}
+
+	return named
+}
+
+// tx is a no-op in ModeReplay.
+type tx struct {
+	real driver.Tx
+}
+
+// Commit implements driver.Tx.
+func (t tx) Commit() error {
+	if t.real == nil {
+		return nil
+	}
+
+	return t.real.Commit()
+}
+
+// Rollback implements driver.Tx.
+func (t tx) Rollback() error {
+	if t.real == nil {
+		return nil
+	}
+
+	return t.real.Rollback()
+}
+
+type execResult struct {
+	*result
+}
+
+// LastInsertId implements driver.Result.
+func (r execResult) LastInsertId() (int64, error) {
+	if r.result == nil {
+		return 0, nil
+	}
+
+	return r.LastInsertID, r.LastInsertIDErr.err()
+}
+
+// RowsAffected implements driver.Result.
+func (r execResult) RowsAffected() (int64, error) {
+	if r.result == nil {
+		return 0, nil
+	}
+
+	return r.result.RowsAffected, r.RowsAffectedErr.err()
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	err     error
+}
+
+func newRows(i *interaction) (*rows, error) {
+	r := &rows{
+		columns: i.Columns,
+		values:  make([][]driver.Value, len(i.Rows)),
+		err:     i.Err.err(),
+	}
+	for j := range i.Rows {
+		r.values[j] = make([]driver.Value, len(i.Rows[j]))
+		for k := range i.Rows[j] {
+			var err error
+			r.values[j][k], err = i.Rows[j][k].decode()
+			if err != nil {
+				return nil, fmt.Errorf("decode: %w", err)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Columns implements driver.Rows.
+func (r *rows) Columns() []string { return r.columns }
+
+// Close implements driver.Rows.
+func (r *rows) Close() error { return nil }
+
+// Next implements driver.Rows.
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package sqltest_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+const fakeDriverName = "sqltest-fake"
+
+var fake = &fakeDriver{}
+
+//nolint:gochecknoinits // Driver must be registered once.
+func init() {
+	stdsql.Register(fakeDriverName, fake)
+	sql.RegisterDriverError(func(err error) bool { return errors.As(err, new(*fakeError)) })
+}
+
+// fakeError is a driver error which isn't pq.Error.
+type fakeError struct{ msg string }
+
+func (e *fakeError) Error() string { return e.msg }
+
+// fakeDriver plays the role of real database for recording.
+type fakeDriver struct {
+	queries int64
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	atomic.AddInt64(&c.d.queries, 1)
+	if query == "INSERT INTO users (name) VALUES ($1)" && args[0].Value == "duplicate" {
+		return nil, &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}
+	}
+	if query == "INSERT INTO users (name) VALUES ($1)" && args[0].Value == "broken" {
+		return nil, &fakeError{msg: "connection is broken"}
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	atomic.AddInt64(&c.d.queries, 1)
+	return &fakeRows{values: [][]driver.Value{{args[0].Value, "alice"}}}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{ values [][]driver.Value }
+
+func (*fakeRows) Columns() []string { return []string{"id", "name"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type dsn string
+
+func (d dsn) DSN() (string, error) { return string(d), nil }
+
+type user struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type repo struct{ db *sql.DB }
+
+func (r repo) GetUser(id int64) (u user, err error) {
+	err = r.db.NoTx(func(db *sqlx.DB) error {
+		return db.Get(&u, "SELECT id, name FROM users WHERE id = $1", id)
+	})
+	return u, err
+}
+
+func (r repo) AddUser(ctx context.Context, name string) error {
+	return r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		_, err := tx.Exec("INSERT INTO users (name) VALUES ($1)", name)
+		return err
+	})
+}
+
+func TestCassette(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cfg := sql.Config{}
+
+	run := func(t *testing.T, cassette sqltest.Cassette) repo {
+		t.Helper()
+		r := require.New(t)
+
+		db, err := sql.New(ctx, sqltest.DriverName, cfg, cassette)
+		r.NoError(err)
+		t.Cleanup(func() { r.NoError(db.Close()) })
+		repo := repo{db: db}
+
+		u, err := repo.GetUser(42)
+		r.NoError(err)
+		r.Equal(user{ID: 42, Name: "alice"}, u)
+		r.NoError(repo.AddUser(ctx, "bob"))
+		err = repo.AddUser(ctx, "duplicate")
+		pqErr := new(pq.Error)
+		r.True(errors.As(err, &pqErr))
+		r.Equal(pq.ErrorCode("23505"), pqErr.Code)
+		err = repo.AddUser(ctx, "broken")
+		r.EqualError(errors.Unwrap(err), "connection is broken")
+		r.NotErrorIs(err, sql.ErrProgrammingBug)
+		if cassette.Mode == sqltest.ModeReplay {
+			replayed := new(sqltest.ReplayedError)
+			r.ErrorAs(err, &replayed)
+			r.Equal("*sqltest_test.fakeError", replayed.Type)
+		}
+
+		return repo
+	}
+
+	t.Run("record", func(t *testing.T) {
+		queries := atomic.LoadInt64(&fake.queries)
+		cassette := sqltest.NewCassette(t, fakeDriverName, dsn(""))
+		cassette.Path = path
+		cassette.Mode = sqltest.ModeRecord
+		run(t, cassette)
+		require.Equal(t, queries+4, atomic.LoadInt64(&fake.queries))
+		_, err := os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist, "cassette is written on close")
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		cassette := sqltest.NewCassette(t, "", nil)
+		cassette.Path = path
+		cassette.Mode = sqltest.ModeReplay
+		repo := run(t, cassette)
+
+		_, err := repo.GetUser(42)
+		require.ErrorIs(t, err, sqltest.ErrUnexpectedQuery)
+		_, err = repo.GetUser(1)
+		require.ErrorIs(t, err, sqltest.ErrUnexpectedQuery)
+	})
+}
+
+func TestCassette_NoSession(t *testing.T) {
+	t.Parallel()
+
+	_, err := sqltest.Cassette{Mode: sqltest.ModeReplay}.DSN()
+	require.ErrorIs(t, err, sqltest.ErrNoSession)
+}
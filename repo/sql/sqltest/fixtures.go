@@ -0,0 +1,375 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+// Errors.
+var (
+	ErrUnknownFormat = errors.New("unknown fixtures format")
+	ErrUnknownRef    = errors.New("unknown reference")
+	ErrDuplicateRef  = errors.New("duplicate reference")
+	ErrRefCycle      = errors.New("reference cycle")
+)
+
+// RefKey is a row key which names row for references.
+const RefKey = "_ref"
+
+// Row is a table row, column values may be templates:
+//   - {{now}} is the same time for all rows,
+//   - {{uuid}} is a new random UUID,
+//   - {{ref "name" "column"}} is a column of row named by RefKey, which
+//     is inserted before.
+type Row map[string]interface{}
+
+// Fixtures are rows keyed by table name, which are decoded from YAML or JSON:
+//
+//	users:
+//	  - _ref: alice
+//	    name: Alice
+//	posts:
+//	  - author_id: '{{ref "alice" "id"}}'
+//	    created_at: '{{now}}'
+type Fixtures map[string][]Row
+
+// LoadFixtures loads and merges fixtures from *.yaml, *.yml and *.json files.
+func LoadFixtures(fsys fs.FS, paths ...string) (Fixtures, error) {
+	fixtures := make(Fixtures)
+	for _, p := range paths {
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("fs.ReadFile: %w", err)
+		}
+
+		var file Fixtures
+		switch path.Ext(p) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(b, &file)
+		case ".json":
+			decoder := json.NewDecoder(bytes.NewReader(b))
+			decoder.UseNumber()
+			err = decoder.Decode(&file)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, p)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+
+		for table, rows := range file {
+			fixtures[table] = append(fixtures[table], rows...)
+		}
+	}
+
+	return fixtures, nil
+}
+
+// fixtureRow is a row prepared for inserting.
+type fixtureRow struct {
+	table   string
+	ref     string
+	columns []string
+	values  []interface{}
+	deps    []string
+}
+
+// Insert inserts all rows inside one transaction: rows are inserted in
+// dependency order (table name order otherwise), sequences of inserted tables
+// are reset after that. Returns inserted rows keyed by reference name.
+func (f Fixtures) Insert(ctx context.Context, db *sql.DB) (map[string]Row, error) {
+	now := time.Now().UTC()
+	rows, err := f.prepare(now)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]Row)
+	err = db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		for _, row := range rows {
+			inserted, err := insertRow(ctx, tx, row, now, refs)
+			if err != nil {
+				return fmt.Errorf("insert into %s: %w", row.table, err)
+			}
+			if row.ref != "" {
+				refs[row.ref] = inserted
+			}
+		}
+
+		schema, ok := currentSchema(ctx, tx)
+		if !ok {
+			return nil
+		}
+		for _, table := range f.tables() {
+			err := resetSequences(ctx, tx, schema, table)
+			if err != nil {
+				return fmt.Errorf("reset sequences of %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+func (f Fixtures) tables() []string {
+	tables := make([]string, 0, len(f))
+	for table := range f {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	return tables
+}
+
+// prepare validates fixtures and returns rows in dependency order.
+func (f Fixtures) prepare(now time.Time) ([]*fixtureRow, error) {
+	var rows []*fixtureRow
+	byRef := make(map[string]*fixtureRow)
+	for _, table := range f.tables() {
+		for i, values := range f[table] {
+			row, err := newFixtureRow(table, values, now)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", table, i, err)
+			}
+			if row.ref != "" {
+				if _, ok := byRef[row.ref]; ok {
+					return nil, fmt.Errorf("%w: %s", ErrDuplicateRef, row.ref)
+				}
+				byRef[row.ref] = row
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*fixtureRow]int)
+	sorted := make([]*fixtureRow, 0, len(rows))
+	var visit func(row *fixtureRow) error
+	visit = func(row *fixtureRow) error {
+		switch state[row] {
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrRefCycle, row.ref)
+		case visited:
+			return nil
+		}
+
+		state[row] = visiting
+		for _, dep := range row.deps {
+			depRow, ok := byRef[dep]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrUnknownRef, dep)
+			}
+			err := visit(depRow)
+			if err != nil {
+				return err
+			}
+		}
+		state[row] = visited
+		sorted = append(sorted, row)
+
+		return nil
+	}
+	for _, row := range rows {
+		err := visit(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+func newFixtureRow(table string, values Row, now time.Time) (*fixtureRow, error) {
+	row := &fixtureRow{table: table}
+	for column := range values {
+		if column != RefKey {
+			row.columns = append(row.columns, column)
+		}
+	}
+	sort.Strings(row.columns)
+
+	if ref, ok := values[RefKey]; ok {
+		row.ref = fmt.Sprint(ref)
+	}
+
+	for _, column := range row.columns {
+		value, err := fixtureValue(values[column])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", column, err)
+		}
+
+		if tmpl, ok := value.(*template.Template); ok {
+			// Dry run for collecting dependencies.
+			var buf bytes.Buffer
+			err = tmpl.Funcs(templateFuncs(now, func(name, _ string) (interface{}, error) {
+				row.deps = append(row.deps, name)
+				return "", nil
+			})).Execute(&buf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", column, err)
+			}
+		}
+		row.values = append(row.values, value)
+	}
+
+	return row, nil
+}
+
+// fixtureValue returns value for inserting or template.
+func fixtureValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("").Funcs(templateFuncs(time.Time{}, nil)).Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("template.Parse: %w", err)
+		}
+		return tmpl, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		return string(b), nil
+	default:
+		return v, nil
+	}
+}
+
+func templateFuncs(now time.Time, ref func(name, column string) (interface{}, error)) template.FuncMap {
+	return template.FuncMap{
+		"now": func() string {
+			return now.Format(time.RFC3339Nano)
+		},
+		"uuid": newUUID,
+		"ref":  ref,
+	}
+}
+
+func insertRow(ctx context.Context, tx *sqlx.Tx, row *fixtureRow, now time.Time, refs map[string]Row) (Row, error) {
+	ref := func(name, column string) (interface{}, error) {
+		value, ok := refs[name][column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s", ErrUnknownRef, name, column)
+		}
+		if t, ok := value.(time.Time); ok {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return value, nil
+	}
+
+	columns := make([]string, len(row.columns))
+	placeholders := make([]string, len(row.columns))
+	args := make([]interface{}, len(row.columns))
+	for i := range row.columns {
+		columns[i] = pq.QuoteIdentifier(row.columns[i])
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = row.values[i]
+
+		if tmpl, ok := row.values[i].(*template.Template); ok {
+			var buf bytes.Buffer
+			err := tmpl.Funcs(templateFuncs(now, ref)).Execute(&buf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", row.columns[i], err)
+			}
+			args[i] = buf.String()
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+		quoteTable(row.table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	inserted := make(Row)
+	err := tx.QueryRowxContext(ctx, query, args...).MapScan(inserted)
+	if err != nil {
+		return nil, err
+	}
+	for column, value := range inserted {
+		if b, ok := value.([]byte); ok {
+			inserted[column] = string(b)
+		}
+	}
+
+	return inserted, nil
+}
+
+// currentSchema returns current schema of PostgreSQL compatible database,
+// it returns false for other databases (e.g. SQLite), which have no sequences.
+func currentSchema(ctx context.Context, tx *sqlx.Tx) (schema string, ok bool) {
+	err := sql.Savepoint(ctx, tx, "sqltest_current_schema", func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &schema, `SELECT current_schema()`)
+	})
+
+	return schema, err == nil
+}
+
+// resetSequences sets serial sequences of the table after max column value,
+// so next inserted rows don't conflict with fixtures.
+// Table without schema is looked up in the default schema.
+func resetSequences(ctx context.Context, tx *sqlx.Tx, defaultSchema, table string) error {
+	schema, name := defaultSchema, table
+	if i := strings.LastIndexByte(table, '.'); i != -1 {
+		schema, name = table[:i], table[i+1:]
+	}
+
+	var columns []struct {
+		Name     string `db:"column_name"`
+		Sequence string `db:"sequence"`
+	}
+	err := tx.SelectContext(ctx, &columns, `
+		SELECT column_name, pg_get_serial_sequence($1, column_name) AS sequence
+		FROM information_schema.columns
+		WHERE table_schema = $2 AND table_name = $3 AND pg_get_serial_sequence($1, column_name) IS NOT NULL`,
+		quoteTable(table), schema, name)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`SELECT setval($1, (SELECT COALESCE(MAX(%s), 0) + 1 FROM %s), false)`,
+			pq.QuoteIdentifier(column.Name), quoteTable(table)), column.Sequence)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quoteTable quotes optionally schema-qualified table name.
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i := range parts {
+		parts[i] = pq.QuoteIdentifier(parts[i])
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,143 @@
+package sqltest_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+var fixturesFS = fstest.MapFS{
+	"users.yaml": {Data: []byte(`
+users:
+  - _ref: alice
+    id: 2
+    name: Alice
+`)},
+	"posts.json": {Data: []byte(`{
+  "posts": [
+    {"author_id": "{{ref \"alice\" \"id\"}}", "uuid": "{{uuid}}", "created_at": "{{now}}", "meta": {"tags": ["go"]}},
+    {"_ref": "reply", "author_id": "{{ref \"bob\" \"id\"}}", "uuid": "{{uuid}}", "created_at": "{{now}}"}
+  ],
+  "users": [
+    {"_ref": "bob", "id": 3, "name": "Bob"}
+  ]
+}`)},
+	"bad.txt": {Data: []byte(`users: []`)},
+}
+
+var postsMigrations = sqltest.Migrations{
+	FS: fstest.MapFS{
+		"migrate/003_posts.sql": {Data: []byte(`
+CREATE TABLE posts (
+	id         INTEGER PRIMARY KEY,
+	author_id  INTEGER NOT NULL REFERENCES users (id),
+	uuid       TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	meta       TEXT
+);
+`)},
+	},
+	Dir: "migrate",
+}
+
+type post struct {
+	AuthorID int64   `db:"author_id"`
+	UUID     string  `db:"uuid"`
+	Meta     *string `db:"meta"`
+}
+
+func (r sqliteRepo) Posts() (posts []post, err error) {
+	err = r.db.NoTx(func(db *sqlx.DB) error {
+		return db.Select(&posts, `SELECT author_id, uuid, meta FROM posts ORDER BY id`)
+	})
+	return posts, err
+}
+
+func TestFixtures_Insert(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := require.New(t)
+	db := sqltest.NewSQLite(t, sql.Config{}, migrations, postsMigrations)
+
+	fixtures, err := sqltest.LoadFixtures(fixturesFS, "users.yaml", "posts.json")
+	r.NoError(err)
+	refs, err := fixtures.Insert(ctx, db)
+	r.NoError(err)
+	r.Equal("Alice", refs["alice"]["name"])
+	r.Equal(int64(3), refs["reply"]["author_id"])
+
+	posts, err := sqliteRepo{db: db}.Posts()
+	r.NoError(err)
+	r.Len(posts, 2)
+	r.Equal(int64(2), posts[0].AuthorID)
+	r.Equal(int64(3), posts[1].AuthorID)
+	r.NotEqual(posts[0].UUID, posts[1].UUID)
+	r.Equal(`{"tags":["go"]}`, *posts[0].Meta)
+	r.Nil(posts[1].Meta)
+
+	_, err = sqltest.LoadFixtures(fixturesFS, "bad.txt")
+	r.ErrorIs(err, sqltest.ErrUnknownFormat)
+}
+
+var registerSQLite3 sync.Once
+
+func TestFixtures_Insert_DriverName(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := require.New(t)
+	sqltest.RegisterSQLite()
+	registerSQLite3.Do(func() {
+		db, err := stdsql.Open(sqltest.SQLiteDriverName, "")
+		r.NoError(err)
+		stdsql.Register("sqlite3", db.Driver())
+		r.NoError(db.Close())
+		sqlx.BindDriver("sqlite3", sqlx.DOLLAR)
+	})
+
+	db, err := sql.New(ctx, "sqlite3", sql.Config{}, sqltest.SQLiteMemory{})
+	r.NoError(err)
+	t.Cleanup(func() { r.NoError(db.Close()) })
+	r.NoError(migrations.Apply(ctx, db))
+	r.NoError(postsMigrations.Apply(ctx, db))
+
+	fixtures, err := sqltest.LoadFixtures(fixturesFS, "users.yaml", "posts.json")
+	r.NoError(err)
+	_, err = fixtures.Insert(ctx, db)
+	r.NoError(err)
+}
+
+func TestFixtures_InsertErr(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := map[string]struct {
+		fixtures sqltest.Fixtures
+		want     error
+	}{
+		"unknown_ref":   {sqltest.Fixtures{"users": {{"id": `{{ref "bob" "id"}}`}}}, sqltest.ErrUnknownRef},
+		"duplicate_ref": {sqltest.Fixtures{"users": {{"_ref": "bob"}, {"_ref": "bob"}}}, sqltest.ErrDuplicateRef},
+		"cycle": {sqltest.Fixtures{"users": {
+			{"_ref": "alice", "name": `{{ref "bob" "name"}}`},
+			{"_ref": "bob", "name": `{{ref "alice" "name"}}`},
+		}}, sqltest.ErrRefCycle},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tc.fixtures.Insert(ctx, nil)
+			require.ErrorIs(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+package sqltest
+
+//go:generate stringer -type=Mode -linecomment
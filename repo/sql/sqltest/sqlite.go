@@ -0,0 +1,180 @@
+package sqltest
+
+import (
+	"context"
+	"crypto/rand"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+// SQLiteDriverName is a name of pure-Go SQLite driver.
+const SQLiteDriverName = "sqlite"
+
+var registerSQLite sync.Once
+
+// RegisterSQLite installs dialect shim, which makes SQLite closer to
+// PostgreSQL/CockroachDB. It changes process-wide state of SQLiteDriverName:
+// - sqlx named queries are compiled to $1 placeholders,
+// - SQLite errors aren't programming bugs for sql.DB,
+// - now() and gen_random_uuid() functions are added to every connection.
+//
+// It's called by NewSQLite, call it before using SQLiteMemory directly.
+// It's safe to call it many times.
+func RegisterSQLite() {
+	registerSQLite.Do(func() {
+		sqlx.BindDriver(SQLiteDriverName, sqlx.DOLLAR)
+		sql.RegisterDriverError(func(err error) bool { return errors.As(err, new(*sqlite.Error)) })
+		sqlite.MustRegisterScalarFunction("now", 0, sqliteNow)
+		sqlite.MustRegisterScalarFunction("gen_random_uuid", 0, sqliteUUID)
+	})
+}
+
+func sqliteNow(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+	return time.Now().UTC().Format(time.RFC3339Nano), nil
+}
+
+func sqliteUUID(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+	return newUUID()
+}
+
+// newUUID returns random UUID v4.
+func newUUID() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4.
+	b[8] = b[8]&0x3f | 0x80 // Variant RFC 4122.
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+var _ sql.Connector = SQLiteMemory{}
+
+// SQLiteMemory is a connector for in-memory SQLite database (use it with
+// SQLiteDriverName after RegisterSQLite). Database exists while it has open connections,
+// connectors with the same Name share database, empty Name means new
+// database for every DSN call.
+type SQLiteMemory struct {
+	Name string
+}
+
+// DSN implements sql.Connector.
+func (c SQLiteMemory) DSN() (string, error) {
+	name := c.Name
+	if name == "" {
+		name = "sqltest" + newSessionID()
+	}
+
+	values := url.Values{}
+	values.Set("mode", "memory")
+	values.Set("cache", "shared")
+	values.Add("_pragma", "foreign_keys(1)")
+	values.Add("_pragma", "busy_timeout(5000)")
+
+	return "file:" + url.PathEscape(name) + "?" + values.Encode(), nil
+}
+
+// Migrations applies SQL migrations to test database.
+type Migrations struct {
+	FS  fs.FS
+	Dir string
+}
+
+// Down migration markers, everything after marker is ignored.
+var downMarkers = []string{"-- +goose Down", "-- +migrate Down"}
+
+// Apply executes every *.sql file of Dir in lexical order inside one
+// transaction. Files *.down.sql and down sections of goose and sql-migrate
+// files are skipped.
+func (m Migrations) Apply(ctx context.Context, db *sql.DB) error {
+	paths, err := fs.Glob(m.FS, path.Join(m.Dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("fs.Glob: %w", err)
+	}
+
+	var names, migrations []string
+	for _, p := range paths {
+		if strings.HasSuffix(p, ".down.sql") {
+			continue
+		}
+
+		b, err := fs.ReadFile(m.FS, p)
+		if err != nil {
+			return fmt.Errorf("fs.ReadFile: %w", err)
+		}
+
+		migration := string(b)
+		for _, marker := range downMarkers {
+			if i := strings.Index(migration, marker); i != -1 {
+				migration = migration[:i]
+			}
+		}
+		names = append(names, p)
+		migrations = append(migrations, migration)
+	}
+
+	return db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		for i := range migrations {
+			_, err := tx.ExecContext(ctx, migrations[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", names[i], err)
+			}
+		}
+		return nil
+	})
+}
+
+// NewSQLite returns DB connected to new in-memory SQLite database with
+// applied migrations. Database is closed on test cleanup.
+func NewSQLite(t testing.TB, cfg sql.Config, migrations ...Migrations) *sql.DB {
+	t.Helper()
+	RegisterSQLite()
+
+	ctx := context.Background()
+	conn := SQLiteMemory{Name: "sqltest" + newSessionID()}
+	dsn, err := conn.DSN()
+	if err != nil {
+		t.Fatalf("SQLiteMemory.DSN: %s", err)
+	}
+
+	// Keeps database alive when pool closes idle connections.
+	keeper, err := stdsql.Open(SQLiteDriverName, dsn)
+	if err == nil {
+		err = keeper.PingContext(ctx)
+	}
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	t.Cleanup(func() { _ = keeper.Close() })
+
+	db, err := sql.New(ctx, SQLiteDriverName, cfg, conn)
+	if err != nil {
+		t.Fatalf("sql.New: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, m := range migrations {
+		err = m.Apply(ctx, db)
+		if err != nil {
+			t.Fatalf("Migrations.Apply: %s", err)
+		}
+	}
+
+	return db
+}
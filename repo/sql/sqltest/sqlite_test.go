@@ -0,0 +1,88 @@
+package sqltest_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"modernc.org/sqlite"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+	"github.com/Meat-Hook/framework/repo/sql/sqltest"
+)
+
+var migrations = sqltest.Migrations{
+	FS: fstest.MapFS{
+		"migrate/001_users.sql": {Data: []byte(`
+-- +goose Up
+CREATE TABLE users (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	uuid TEXT NOT NULL DEFAULT (gen_random_uuid()),
+	created_at TEXT NOT NULL DEFAULT (now())
+);
+-- +goose Down
+DROP TABLE users;
+`)},
+		"migrate/002_admin.up.sql":   {Data: []byte(`INSERT INTO users (id, name) VALUES (1, 'admin');`)},
+		"migrate/002_admin.down.sql": {Data: []byte(`DELETE FROM users;`)},
+	},
+	Dir: "migrate",
+}
+
+type sqliteRepo struct{ db *sql.DB }
+
+func (r sqliteRepo) AddUser(ctx context.Context, u user) error {
+	return r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExecContext(ctx, `INSERT INTO users (id, name) VALUES (:id, :name)`, u)
+		return err
+	})
+}
+
+func (r sqliteRepo) GetUser(id int64) (u user, err error) {
+	err = r.db.NoTx(func(db *sqlx.DB) error {
+		return db.Get(&u, `SELECT id, name FROM users WHERE id = $1`, id)
+	})
+	return u, err
+}
+
+func (r sqliteRepo) UUID(id int64) (uuid string, err error) {
+	err = r.db.NoTx(func(db *sqlx.DB) error {
+		return db.Get(&uuid, `SELECT uuid FROM users WHERE id = $1`, id)
+	})
+	return uuid, err
+}
+
+func TestNewSQLite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := require.New(t)
+	repo := sqliteRepo{db: sqltest.NewSQLite(t, sql.Config{}, migrations)}
+
+	u, err := repo.GetUser(1)
+	r.NoError(err)
+	r.Equal(user{ID: 1, Name: "admin"}, u)
+
+	r.NoError(repo.AddUser(ctx, user{ID: 2, Name: "alice"}))
+	u, err = repo.GetUser(2)
+	r.NoError(err)
+	r.Equal(user{ID: 2, Name: "alice"}, u)
+
+	uuid, err := repo.UUID(2)
+	r.NoError(err)
+	r.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+
+	err = repo.AddUser(ctx, user{ID: 3, Name: "alice"})
+	r.Error(err)
+	sqliteErr := new(sqlite.Error)
+	r.True(errors.As(err, &sqliteErr))
+
+	// Databases are isolated.
+	_, err = sqliteRepo{db: sqltest.NewSQLite(t, sql.Config{}, migrations)}.GetUser(2)
+	r.ErrorIs(err, stdsql.ErrNoRows)
+}
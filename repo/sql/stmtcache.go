@@ -0,0 +1,304 @@
+package sql
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Meat-Hook/framework/reflectx"
+)
+
+const (
+	labelResult = "result" // Value: "hit" or "miss".
+)
+
+// StmtCacheMetrics contains metrics of prepared statement cache, may be shared by many DB.
+type StmtCacheMetrics struct {
+	lookups       *prometheus.CounterVec
+	invalidations prometheus.Counter
+}
+
+// NewStmtCacheMetrics registers and returns metrics of prepared statement cache:
+// - amount of cache hits and misses,
+// - amount of cache invalidations.
+func NewStmtCacheMetrics(reg *prometheus.Registry, namespace, subsystem string) *StmtCacheMetrics {
+	metric := &StmtCacheMetrics{}
+
+	metric.lookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stmt_cache_lookups_total",
+			Help:      "Amount of prepared statement cache lookups.",
+		},
+		[]string{labelResult},
+	)
+	reg.MustRegister(metric.lookups)
+	metric.invalidations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stmt_cache_invalidations_total",
+			Help:      "Amount of prepared statement cache invalidations.",
+		},
+	)
+	reg.MustRegister(metric.invalidations)
+
+	for _, result := range []string{"hit", "miss"} {
+		metric.lookups.With(prometheus.Labels{labelResult: result})
+	}
+
+	return metric
+}
+
+type stmtKey struct {
+	query string
+	named bool
+}
+
+type cachedStmt struct {
+	key   stmtKey
+	stmt  *sqlx.Stmt
+	named *sqlx.NamedStmt
+
+	refs    int
+	evicted bool
+}
+
+// prepareStmt prepares statement without tags of ctx, because it's shared by callers.
+func prepareStmt(ctx context.Context, db *sqlx.DB, key stmtKey) (stmt *cachedStmt, err error) {
+	ctx = withStaticTags(ctx)
+	stmt = &cachedStmt{key: key}
+	if key.named {
+		stmt.named, err = db.PrepareNamedContext(ctx, key.query)
+	} else {
+		stmt.stmt, err = db.PreparexContext(ctx, key.query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (c *cachedStmt) close() {
+	if c.named != nil {
+		_ = c.named.Close()
+	} else {
+		_ = c.stmt.Close()
+	}
+}
+
+// stmtCache is LRU cache of prepared statements keyed by query text.
+// Statements evicted from cache are closed after they're released.
+type stmtCache struct {
+	size    int
+	metrics *StmtCacheMetrics
+
+	mu    sync.Mutex
+	items map[stmtKey]*list.Element
+	lru   *list.List
+}
+
+func newStmtCache(size int, metrics *StmtCacheMetrics) *stmtCache {
+	return &stmtCache{
+		size:    size,
+		metrics: metrics,
+		items:   make(map[stmtKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// acquire returns statement for the query, it must be released after use.
+func (c *stmtCache) acquire(ctx context.Context, db *sqlx.DB, key stmtKey) (*cachedStmt, error) {
+	if stmt := c.lookup(key); stmt != nil {
+		c.observe("hit")
+		return stmt, nil
+	}
+	c.observe("miss")
+
+	stmt, err := prepareStmt(ctx, db, key)
+	if err != nil {
+		c.invalidateOn(err)
+		return nil, err
+	}
+
+	return c.store(stmt), nil
+}
+
+func (c *stmtCache) lookup(key stmtKey) *cachedStmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	stmt := el.Value.(*cachedStmt)
+	stmt.refs++
+
+	return stmt
+}
+
+func (c *stmtCache) store(stmt *cachedStmt) *cachedStmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Statement may be prepared concurrently.
+	if el, ok := c.items[stmt.key]; ok {
+		stmt.close()
+		stmt = el.Value.(*cachedStmt)
+		stmt.refs++
+		return stmt
+	}
+
+	stmt.refs++
+	c.items[stmt.key] = c.lru.PushFront(stmt)
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+
+	return stmt
+}
+
+func (c *stmtCache) release(stmt *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stmt.refs--
+	if stmt.evicted && stmt.refs == 0 {
+		stmt.close()
+	}
+}
+
+// evict must be called with locked mu.
+func (c *stmtCache) evict(el *list.Element) {
+	stmt := c.lru.Remove(el).(*cachedStmt)
+	delete(c.items, stmt.key)
+	stmt.evicted = true
+	if stmt.refs == 0 {
+		stmt.close()
+	}
+}
+
+// invalidate evicts all statements.
+func (c *stmtCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.lru.Len() != 0 {
+		c.evict(c.lru.Back())
+	}
+}
+
+// invalidateOn evicts all statements if err means that they may be broken.
+func (c *stmtCache) invalidateOn(err error) {
+	if c == nil || !invalidatesStmts(err) {
+		return
+	}
+
+	c.invalidate()
+	if c.metrics != nil {
+		c.metrics.invalidations.Inc()
+	}
+}
+
+func (c *stmtCache) observe(result string) {
+	if c.metrics != nil {
+		c.metrics.lookups.With(prometheus.Labels{labelResult: result}).Inc()
+	}
+}
+
+// invalidatesStmts reports whether err is connection or schema change error.
+func invalidatesStmts(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	pqErr := new(pq.Error)
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch {
+	case pqErr.Code.Class() == "08": // Connection exception.
+		return true
+	case pqErr.Code == "26000": // Invalid SQL statement name, e.g. prepared statement doesn't exist.
+		return true
+	case pqErr.Code == "0A000": // Feature not supported.
+		return strings.Contains(pqErr.Message, "cached plan must not change result type")
+	default:
+		return false
+	}
+}
+
+// Stmt provides DAL method wrapper like NoTxContext, but calls f with cached
+// prepared statement for the query. Statement mustn't be used after f returns.
+// Cache is enabled by Config.StmtCacheSize, otherwise statement is
+// prepared for every call.
+func (db *DB) Stmt(ctx context.Context, query string, f func(*sqlx.Stmt) error) error {
+	return db.stmt(ctx, reflectx.CallerMethodName(1), stmtKey{query: query}, func(stmt *cachedStmt) error {
+		return f(stmt.stmt)
+	})
+}
+
+// NamedStmt is like Stmt, but for query with named parameters.
+func (db *DB) NamedStmt(ctx context.Context, query string, f func(*sqlx.NamedStmt) error) error {
+	return db.stmt(ctx, reflectx.CallerMethodName(1), stmtKey{query: query, named: true}, func(stmt *cachedStmt) error {
+		return f(stmt.named)
+	})
+}
+
+// TxStmt is like Stmt, but statement is bound to tx, it must be called
+// from DB.Tx callback. Metrics are collected and errors are wrapped by DB.Tx.
+func (db *DB) TxStmt(ctx context.Context, tx *sqlx.Tx, query string, f func(*sqlx.Stmt) error) error {
+	return db.strict(reflectx.CallerMethodName(1), db.withStmt(ctx, stmtKey{query: query}, func(stmt *cachedStmt) error {
+		txStmt := tx.StmtxContext(ctx, stmt.stmt)
+		defer txStmt.Close()
+		return f(txStmt)
+	}))
+}
+
+// TxNamedStmt is like TxStmt, but for query with named parameters.
+func (db *DB) TxNamedStmt(ctx context.Context, tx *sqlx.Tx, query string, f func(*sqlx.NamedStmt) error) error {
+	return db.strict(reflectx.CallerMethodName(1), db.withStmt(ctx, stmtKey{query: query, named: true}, func(stmt *cachedStmt) error {
+		txStmt := tx.NamedStmtContext(ctx, stmt.named)
+		defer txStmt.Close()
+		return f(txStmt)
+	}))
+}
+
+// stmt provides the same wrapper as noTx, so tenant ctx is rejected, because
+// statement would run with default search_path.
+func (db *DB) stmt(ctx context.Context, methodName string, key stmtKey, f func(*cachedStmt) error) error {
+	return db.noTx(ctx, methodName, nil, func(ctx context.Context, _ *sqlx.DB) error {
+		return db.withStmt(ctx, key, f)
+	})
+}
+
+func (db *DB) withStmt(ctx context.Context, key stmtKey, f func(*cachedStmt) error) error {
+	if db.stmts == nil {
+		stmt, err := prepareStmt(ctx, db.conn, key)
+		if err != nil {
+			return err
+		}
+		defer stmt.close()
+		return f(stmt)
+	}
+
+	stmt, err := db.stmts.acquire(ctx, db.conn, key)
+	if err != nil {
+		return err
+	}
+	defer db.stmts.release(stmt)
+
+	return f(stmt)
+}
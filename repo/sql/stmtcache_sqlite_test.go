@@ -0,0 +1,117 @@
+package sql_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo/sql"
+)
+
+type stmtRepo struct {
+	db *sql.DB
+}
+
+func (r stmtRepo) GetOne(ctx context.Context) (n int, err error) {
+	err = r.db.Stmt(ctx, `SELECT 1`, func(stmt *sqlx.Stmt) error {
+		return stmt.GetContext(ctx, &n)
+	})
+	return n, err
+}
+
+func (r stmtRepo) GetMissing(ctx context.Context) error {
+	var dest struct {
+		A int `db:"a"`
+	}
+	return r.db.Stmt(ctx, `SELECT 1 AS b`, func(stmt *sqlx.Stmt) error {
+		return stmt.GetContext(ctx, &dest)
+	})
+}
+
+func (r stmtRepo) TxGetMissing(ctx context.Context) error {
+	var dest struct {
+		A int `db:"a"`
+	}
+	return r.db.Tx(ctx, nil, func(tx *sqlx.Tx) error {
+		return r.db.TxStmt(ctx, tx, `SELECT 1 AS b`, func(stmt *sqlx.Stmt) error {
+			return stmt.GetContext(ctx, &dest)
+		})
+	})
+}
+
+func TestDB_Stmt(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	var bugs []*sql.BugError
+	collector := &callsCollector{}
+	db, _ := newRecordingSQLite(t, sql.Config{
+		Metrics:          collector,
+		StmtCacheSize:    1,
+		StrictPolicy:     sql.StrictReport,
+		OnProgrammingBug: func(err *sql.BugError) { bugs = append(bugs, err) },
+		Logger:           log.New(io.Discard, "", 0),
+	})
+	repo := stmtRepo{db: db}
+
+	n, err := repo.GetOne(ctx)
+	r.NoError(err)
+	r.Equal(1, n)
+
+	err = repo.GetMissing(ctx)
+	r.ErrorIs(err, sql.ErrProgrammingBug)
+	r.Contains(err.Error(), "GetMissing: ")
+	r.Len(bugs, 1)
+
+	err = repo.TxGetMissing(ctx)
+	r.ErrorIs(err, sql.ErrProgrammingBug)
+	r.Len(bugs, 2)
+
+	r.Equal([]string{"GetOne:", "GetMissing:", "TxGetMissing:"}, collector.calls)
+}
+
+func TestDB_Stmt_Tenant(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := sql.WithTenant(context.Background(), "tenant")
+
+	db, recording := newRecordingSQLite(t, sql.Config{
+		Tenants:       sql.TenantRegistry{"tenant": "tenant"},
+		StmtCacheSize: 1,
+	})
+	_, err := stmtRepo{db: db}.GetOne(ctx)
+	r.ErrorIs(err, sql.ErrTenantNoTx)
+	r.Contains(err.Error(), "GetOne: ")
+	r.Empty(recording.all())
+}
+
+type requestIDKey struct{}
+
+func TestDB_Stmt_QueryTags(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	db, recording := newRecordingSQLite(t, sql.Config{
+		QueryTags: &sql.QueryTags{
+			Service: "users",
+			FromContext: func(ctx context.Context) map[string]string {
+				requestID, _ := ctx.Value(requestIDKey{}).(string)
+				return map[string]string{"request_id": requestID}
+			},
+		},
+		StmtCacheSize: 1,
+	})
+	repo := stmtRepo{db: db}
+
+	for _, requestID := range []string{"1", "2"} {
+		_, err := repo.GetOne(context.WithValue(context.Background(), requestIDKey{}, requestID))
+		r.NoError(err)
+	}
+
+	r.Equal([]string{"SELECT 1 /*service='users'*/"}, recording.all())
+}
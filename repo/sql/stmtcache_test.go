@@ -0,0 +1,161 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite" // Driver for tests of statements.
+)
+
+func TestInvalidatesStmts(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		given error
+		want  bool
+	}{
+		"nil":              {nil, false},
+		"other":            {errors.New("other"), false},
+		"bad_conn":         {fmt.Errorf("GetUser: %w", driver.ErrBadConn), true},
+		"connection":       {&pq.Error{Code: "08006"}, true},
+		"no_stmt":          {&pq.Error{Code: "26000"}, true},
+		"cached_plan":      {&pq.Error{Code: "0A000", Message: "cached plan must not change result type"}, true},
+		"not_supported":    {&pq.Error{Code: "0A000", Message: "unimplemented"}, false},
+		"unique_violation": {fmt.Errorf("CreateUser: %w", &pq.Error{Code: "23505"}), false},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			r.Equal(tc.want, invalidatesStmts(tc.given))
+		})
+	}
+}
+
+func newStmtCacheDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sqlx.Open: %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+// queries returns cached queries from most to least recently used.
+func (c *stmtCache) queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var queries []string
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		queries = append(queries, el.Value.(*cachedStmt).key.query)
+	}
+
+	return queries
+}
+
+func isClosed(stmt *cachedStmt) bool {
+	var n int
+	return stmt.stmt.Get(&n) != nil
+}
+
+func TestStmtCache_LRU(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	db := newStmtCacheDB(t)
+	cache := newStmtCache(2, nil)
+	use := func(query string) *cachedStmt {
+		stmt, err := cache.acquire(ctx, db, stmtKey{query: query})
+		r.NoError(err)
+		cache.release(stmt)
+		return stmt
+	}
+
+	first, second := use("SELECT 1"), use("SELECT 2")
+	r.Equal([]string{"SELECT 2", "SELECT 1"}, cache.queries())
+
+	r.Same(first, use("SELECT 1"))
+	r.Equal([]string{"SELECT 1", "SELECT 2"}, cache.queries())
+
+	third := use("SELECT 3")
+	r.Equal([]string{"SELECT 3", "SELECT 1"}, cache.queries())
+	r.True(isClosed(second))
+	r.False(isClosed(first))
+	r.False(isClosed(third))
+
+	cache.invalidate()
+	r.Empty(cache.queries())
+	r.True(isClosed(first))
+	r.True(isClosed(third))
+}
+
+func TestStmtCache_Refs(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	db := newStmtCacheDB(t)
+	cache := newStmtCache(1, nil)
+
+	first, err := cache.acquire(ctx, db, stmtKey{query: "SELECT 1"})
+	r.NoError(err)
+	again, err := cache.acquire(ctx, db, stmtKey{query: "SELECT 1"})
+	r.NoError(err)
+	r.Same(first, again)
+	r.Equal(2, first.refs)
+
+	second, err := cache.acquire(ctx, db, stmtKey{query: "SELECT 2"})
+	r.NoError(err)
+	r.Equal([]string{"SELECT 2"}, cache.queries())
+	r.True(first.evicted)
+	r.False(isClosed(first), "evicted statement is closed while it's used")
+
+	cache.release(first)
+	r.False(isClosed(first), "evicted statement is closed while it's used")
+	cache.release(again)
+	r.True(isClosed(first))
+
+	cache.release(second)
+	r.False(isClosed(second))
+}
+
+func TestStmtCache_Metrics(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+
+	db := newStmtCacheDB(t)
+	metrics := NewStmtCacheMetrics(prometheus.NewRegistry(), "", "")
+	cache := newStmtCache(1, metrics)
+	for _, query := range []string{"SELECT 1", "SELECT 1", "SELECT 2", "SELECT 1"} {
+		stmt, err := cache.acquire(ctx, db, stmtKey{query: query})
+		r.NoError(err)
+		cache.release(stmt)
+	}
+
+	r.Equal(1.0, testutil.ToFloat64(metrics.lookups.With(prometheus.Labels{labelResult: "hit"})))
+	r.Equal(3.0, testutil.ToFloat64(metrics.lookups.With(prometheus.Labels{labelResult: "miss"})))
+
+	cache.invalidateOn(errors.New("other"))
+	r.Equal(0.0, testutil.ToFloat64(metrics.invalidations))
+	cache.invalidateOn(driver.ErrBadConn)
+	r.Equal(1.0, testutil.ToFloat64(metrics.invalidations))
+	r.Empty(cache.queries())
+}
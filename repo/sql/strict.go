@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Errors.
+var (
+	// ErrProgrammingBug is matched by errors which are actually programming
+	// bugs (e.g. sqlx errors like `missing destination …`).
+	ErrProgrammingBug = errors.New("programming bug")
+	ErrNoBugReporter  = errors.New("no OnProgrammingBug for StrictReport policy")
+)
+
+// StrictPolicy defines how DB handles errors which are actually programming bugs.
+type StrictPolicy uint8
+
+// Enum.
+const (
+	// StrictPanic panics with original error.
+	StrictPanic StrictPolicy = iota
+	// StrictError returns *BugError.
+	StrictError
+	// StrictReport calls Config.OnProgrammingBug and returns *BugError.
+	StrictReport
+)
+
+// Logger is used for logging programming bugs and other DB issues.
+// It's implemented by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// BugError is returned instead of panic for errors which are
+// actually programming bugs, it matches ErrProgrammingBug.
+type BugError struct {
+	Err   error
+	Stack []byte
+}
+
+// Error implements error.
+func (e *BugError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrProgrammingBug, e.Err)
+}
+
+// Unwrap returns original error.
+func (e *BugError) Unwrap() error {
+	return e.Err
+}
+
+// Is implements errors.Is.
+func (e *BugError) Is(target error) bool {
+	return target == ErrProgrammingBug
+}
+
+var driverErrs struct {
+	sync.RWMutex
+	is []func(error) bool
+}
+
+// RegisterDriverError registers func which reports whether err is returned
+// by database driver, so it isn't considered programming bug. Errors of
+// lib/pq are recognized without registration.
+// It's usually called from init of package which adds support for other driver.
+func RegisterDriverError(is func(error) bool) {
+	driverErrs.Lock()
+	defer driverErrs.Unlock()
+
+	driverErrs.is = append(driverErrs.is, is)
+}
+
+func isDriverError(err error) bool {
+	driverErrs.RLock()
+	defer driverErrs.RUnlock()
+
+	for _, is := range driverErrs.is {
+		if is(err) {
+			return true
+		}
+	}
+
+	return false
+}
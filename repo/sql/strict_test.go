@@ -0,0 +1,80 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Meat-Hook/framework/repo"
+)
+
+type testDriverError struct{}
+
+func (testDriverError) Error() string { return "driver error" }
+
+//nolint:gochecknoinits // Like packages which add support for other drivers.
+func init() {
+	RegisterDriverError(func(err error) bool { return errors.As(err, new(testDriverError)) })
+}
+
+func TestDB_Strict(t *testing.T) {
+	t.Parallel()
+
+	errAny := errors.New("missing destination name")
+	errReturn := errors.New("return")
+	errDriver := fmt.Errorf("wrapped: %w", testDriverError{})
+
+	tests := map[string]struct {
+		policy    StrictPolicy
+		given     error
+		wantErr   error
+		wantPanic bool
+		wantBug   bool
+	}{
+		"nil":            {StrictPanic, nil, nil, false, false},
+		"return_errs":    {StrictPanic, errReturn, errReturn, false, false},
+		"unknown_tenant": {StrictPanic, ErrUnknownTenant, ErrUnknownTenant, false, false},
+		"tenant_no_tx":   {StrictPanic, ErrTenantNoTx, ErrTenantNoTx, false, false},
+		"driver_error":   {StrictPanic, errDriver, errDriver, false, false},
+		"panic":          {StrictPanic, errAny, nil, true, true},
+		"error":          {StrictError, errAny, ErrProgrammingBug, false, true},
+		"report":         {StrictReport, errAny, ErrProgrammingBug, false, true},
+	}
+	for name, tc := range tests {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			var (
+				buf      bytes.Buffer
+				reported *BugError
+			)
+			db := &DB{
+				returnErrs:   []error{errReturn},
+				metrics:      repo.NoMetric{},
+				strictPolicy: tc.policy,
+				onBug:        func(err *BugError) { reported = err },
+				logger:       log.New(&buf, "", 0),
+			}
+
+			if tc.wantPanic {
+				r.PanicsWithError(tc.given.Error(), func() { _ = db.strict("Method", tc.given) })
+			} else {
+				err := db.strict("Method", tc.given)
+				r.ErrorIs(err, tc.wantErr)
+				if tc.wantBug {
+					r.ErrorIs(err, tc.given)
+				}
+			}
+
+			r.Equal(tc.wantBug, buf.Len() != 0)
+			r.Equal(tc.policy == StrictReport, reported != nil)
+		})
+	}
+}
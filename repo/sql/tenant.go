@@ -0,0 +1,113 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// LabelTenant is a metric label which contains tenant name.
+// Metrics must be created with this label (see repo.NewMetrics)
+// and Config.TenantMetrics must be set.
+const LabelTenant = "tenant"
+
+// Errors.
+var (
+	ErrUnknownTenant = errors.New("unknown tenant")
+	ErrInvalidSchema = errors.New("invalid schema name")
+	ErrTenantNoTx    = errors.New("tenant can't be used with NoTx, use Tx or Conn")
+)
+
+// Unquoted lower case identifier, see
+// https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-IDENTIFIERS.
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_$]{0,62}$`)
+
+// TenantRegistry maps tenant name to the name of schema which contains tenant's data.
+type TenantRegistry map[string]string
+
+func (r TenantRegistry) validate() error {
+	for tenant, schema := range r {
+		if !schemaName.MatchString(schema) {
+			return fmt.Errorf("%w: tenant %q: %q", ErrInvalidSchema, tenant, schema)
+		}
+	}
+
+	return nil
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx which carries tenant name.
+// DB.Tx and DB.Conn pin search_path of the connection to the tenant's schema.
+// Tenant must be registered in Config.Tenants, otherwise ErrUnknownTenant is returned.
+// DB.NoTxContext returns ErrTenantNoTx, because it uses connection pool directly.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns tenant name carried by ctx.
+func Tenant(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// tenantSchema returns schema for tenant carried by ctx.
+// Returns empty string if ctx doesn't carry tenant.
+func (db *DB) tenantSchema(ctx context.Context) (string, error) {
+	tenant, ok := Tenant(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	schema, ok := db.tenants[tenant]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTenant, tenant)
+	}
+
+	return schema, nil
+}
+
+// setTenantTx sets search_path for current transaction only.
+func (db *DB) setTenantTx(ctx context.Context, tx *sqlx.Tx) error {
+	schema, err := db.tenantSchema(ctx)
+	if err != nil || schema == "" {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('search_path', $1, true)`, pq.QuoteIdentifier(schema))
+	if err != nil {
+		return fmt.Errorf("set search_path: %w", err)
+	}
+
+	return nil
+}
+
+// setTenantConn sets search_path for pinned connection and returns
+// func for resetting it before connection is released.
+func (db *DB) setTenantConn(ctx context.Context, conn *sqlx.Conn) (reset func() error, err error) {
+	schema, err := db.tenantSchema(ctx)
+	if err != nil || schema == "" {
+		return nil, err
+	}
+
+	return setConnVariable(ctx, conn, "search_path", pq.QuoteIdentifier(schema))
+}
+
+// tenantLabels returns copy of labels with LabelTenant if it's enabled.
+func (db *DB) tenantLabels(ctx context.Context, labels map[string]string) map[string]string {
+	if !db.tenantMetrics {
+		return labels
+	}
+
+	l := make(map[string]string, len(labels)+1)
+	for name, value := range labels {
+		l[name] = value
+	}
+	l[LabelTenant], _ = Tenant(ctx)
+
+	return l
+}
@@ -0,0 +1,36 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantRegistry_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		given   string
+		wantErr error
+	}{
+		{"tenant", nil},
+		{"tenant_1", nil},
+		{"_tenant$1", nil},
+		{"", ErrInvalidSchema},
+		{"Tenant", ErrInvalidSchema},
+		{"1tenant", ErrInvalidSchema},
+		{"tenant; DROP SCHEMA public", ErrInvalidSchema},
+		{"t012345678901234567890123456789012345678901234567890123456789012", ErrInvalidSchema},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.given, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			err := TenantRegistry{"name": tc.given}.validate()
+			r.ErrorIs(err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package sql
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Meat-Hook/framework/repo"
+)
+
+// TxMetrics contains metrics of transactions, may be shared by many DB.
+type TxMetrics struct {
+	duration *prometheus.HistogramVec
+
+	mu       sync.Mutex
+	trackers map[*txTracker]struct{}
+}
+
+// NewTxMetrics registers and returns metrics of transactions:
+// - histogram of transactions duration,
+// - age of the oldest open transaction.
+func NewTxMetrics(reg *prometheus.Registry, namespace, subsystem string) *TxMetrics {
+	metric := &TxMetrics{
+		trackers: make(map[*txTracker]struct{}),
+	}
+
+	metric.duration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tx_duration_seconds",
+			Help:      "Transaction duration.",
+		},
+		[]string{repo.LabelFunc},
+	)
+	reg.MustRegister(metric.duration)
+	reg.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tx_oldest_open_age_seconds",
+			Help:      "Age of the oldest open transaction.",
+		},
+		metric.oldestAge,
+	))
+
+	return metric
+}
+
+func (m *TxMetrics) add(t *txTracker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trackers[t] = struct{}{}
+}
+
+func (m *TxMetrics) remove(t *txTracker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.trackers, t)
+}
+
+func (m *TxMetrics) oldestAge() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var oldest time.Time
+	for t := range m.trackers {
+		for _, tx := range t.openTx() {
+			if oldest.IsZero() || tx.Start.Before(oldest) {
+				oldest = tx.Start
+			}
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+
+	return time.Since(oldest).Seconds()
+}
+
+// OpenTx describes open transaction.
+type OpenTx struct {
+	Method string
+	Start  time.Time
+	// Stack is captured only if Config.LongTxThreshold is set.
+	Stack []byte
+}
+
+// txTracker tracks open transactions of DB.
+type txTracker struct {
+	metrics   *TxMetrics
+	threshold time.Duration
+	logger    Logger
+
+	mu   sync.Mutex
+	open map[*sqlx.Tx]OpenTx
+}
+
+func newTxTracker(metrics *TxMetrics, threshold time.Duration, logger Logger) *txTracker {
+	t := &txTracker{
+		metrics:   metrics,
+		threshold: threshold,
+		logger:    logger,
+		open:      make(map[*sqlx.Tx]OpenTx),
+	}
+	if metrics != nil {
+		metrics.add(t)
+	}
+
+	return t
+}
+
+func (t *txTracker) close() {
+	if t.metrics != nil {
+		t.metrics.remove(t)
+	}
+}
+
+// track starts tracking of transaction and returns func for finishing it,
+// which may be called many times, only the first call is counted.
+func (t *txTracker) track(tx *sqlx.Tx, methodName string) (done func()) {
+	open := OpenTx{
+		Method: methodName,
+		Start:  time.Now(),
+	}
+
+	var timer *time.Timer
+	if t.threshold != 0 {
+		open.Stack = debug.Stack()
+		timer = time.AfterFunc(t.threshold, func() {
+			t.logger.Printf("%s: transaction is open longer than %s\n%s", methodName, t.threshold, open.Stack)
+		})
+	}
+
+	t.mu.Lock()
+	t.open[tx] = open
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if timer != nil {
+				timer.Stop()
+			}
+
+			t.mu.Lock()
+			delete(t.open, tx)
+			t.mu.Unlock()
+
+			if t.metrics != nil {
+				t.metrics.duration.With(prometheus.Labels{repo.LabelFunc: methodName}).Observe(time.Since(open.Start).Seconds())
+			}
+		})
+	}
+}
+
+func (t *txTracker) openTx() []OpenTx {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := make([]OpenTx, 0, len(t.open))
+	for _, tx := range t.open {
+		open = append(open, tx)
+	}
+
+	return open
+}
@@ -0,0 +1,83 @@
+//nolint:testpackage // Testing unexported function.
+package sql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTxTracker(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var buf syncBuffer
+	metrics := NewTxMetrics(prometheus.NewRegistry(), "test", "tx")
+	tracker := newTxTracker(metrics, time.Millisecond, log.New(&buf, "", 0))
+	defer tracker.close()
+
+	r.Zero(metrics.oldestAge())
+
+	done := tracker.track(&sqlx.Tx{}, "Method")
+	open := tracker.openTx()
+	r.Len(open, 1)
+	r.Equal("Method", open[0].Method)
+	r.NotEmpty(open[0].Stack)
+	r.Eventually(func() bool { return metrics.oldestAge() > 0 }, time.Second, time.Millisecond)
+	r.Eventually(func() bool { return buf.String() != "" }, time.Second, time.Millisecond)
+	r.Contains(buf.String(), "Method: transaction is open longer than 1ms")
+
+	done()
+	r.Empty(tracker.openTx())
+	r.Zero(metrics.oldestAge())
+}
+
+func TestDB_Tx_TrackerBeforeHooks(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	db, _ := newCRDB(t, Config{StrictPolicy: StrictError, TxMetrics: NewTxMetrics(prometheus.NewRegistry(), "test", "tx")})
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	var openOnCommit, openOnRollback []OpenTx
+	r.NoError(db.tx(ctx, "Method", nil, nil, func(tx *sqlx.Tx) error {
+		OnCommit(tx, func() { openOnCommit = db.txs.openTx() })
+		return nil
+	}))
+	err := db.tx(ctx, "Method", nil, nil, func(tx *sqlx.Tx) error {
+		OnRollback(tx, func(error) { openOnRollback = db.txs.openTx() })
+		return errRollback
+	})
+	r.ErrorIs(err, errRollback)
+
+	r.NotNil(openOnCommit)
+	r.Empty(openOnCommit)
+	r.NotNil(openOnRollback)
+	r.Empty(openOnRollback)
+}